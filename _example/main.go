@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/libdns/digitalocean"
@@ -37,9 +38,13 @@ func main() {
 	for _, record := range records {
 		fmt.Printf("%s (.%s): %s, %s\n", record.RR().Name, zone, record.RR().Data, record.RR().Type)
 		if record.RR().Name == txtTestName {
-			txtTestId = record.(digitalocean.DNS).ID
+			if id, ok := digitalocean.RecordID(record); ok {
+				txtTestId = strconv.Itoa(id)
+			}
 		} else if record.RR().Name == aTestName {
-			aTestId = record.(digitalocean.DNS).ID
+			if id, ok := digitalocean.RecordID(record); ok {
+				aTestId = strconv.Itoa(id)
+			}
 		}
 	}
 
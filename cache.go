@@ -0,0 +1,157 @@
+package digitalocean
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// zoneCache holds the records of a zone as last read from DigitalOcean.
+type zoneCache struct {
+	entries []godo.DomainRecord
+	fetched time.Time
+}
+
+type noCacheKey struct{}
+
+// WithoutCache returns a context under which the records of a zone are read
+// from DigitalOcean even if they are cached. The cache is updated with what is
+// read.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, noCacheKey{}, true)
+}
+
+// RefreshZone reads the records of the zone from DigitalOcean into the cache.
+func (p *Provider) RefreshZone(ctx context.Context, zone string) error {
+	_, err := p.getDNSEntries(WithoutCache(ctx), p.unFQDN(zone))
+	return err
+}
+
+// InvalidateZone drops the cached records of the zone, so they are read from
+// DigitalOcean the next time they are needed.
+func (p *Provider) InvalidateZone(zone string) {
+	p.updateCache(p.unFQDN(zone), nil)
+}
+
+// cachedEntries returns the cached records of the zone, if caching is enabled,
+// ctx does not bypass the cache and the records are fresh.
+func (p *Provider) cachedEntries(ctx context.Context, zone string) ([]godo.DomainRecord, bool) {
+	if p.CacheTTL <= 0 {
+		return nil, false
+	}
+	if bypass, _ := ctx.Value(noCacheKey{}).(bool); bypass {
+		return nil, false
+	}
+
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+
+	cached, ok := p.cache[zone]
+	if !ok || time.Since(cached.fetched) > p.CacheTTL {
+		return nil, false
+	}
+	return slices.Clone(cached.entries), true
+}
+
+// cacheGeneration returns the number of changes made through the Provider so
+// far. It is taken before reading the zone, so that storeEntries can tell
+// whether what was read is already outdated.
+func (p *Provider) cacheGeneration(zone string) uint64 {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+	return p.cacheSeq
+}
+
+// storeEntries caches the records of the zone read at generation gen, unless
+// the zone may have been changed while they were read. Changes to zones that
+// are not cached are not tracked one by one, so any of them since gen keeps
+// the records out.
+func (p *Provider) storeEntries(zone string, gen uint64, entries []godo.DomainRecord) {
+	if p.CacheTTL <= 0 {
+		return
+	}
+
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+
+	if p.cacheGen[zone] > gen || p.cacheUncached > gen {
+		return
+	}
+
+	// drop the records of other zones that expired, so zones that are no
+	// longer used do not stay in memory
+	now := time.Now()
+	for name, cached := range p.cache {
+		if now.Sub(cached.fetched) > p.CacheTTL {
+			p.dropCache(name)
+		}
+	}
+
+	if p.cache == nil {
+		p.cache = make(map[string]*zoneCache)
+	}
+	p.cache[zone] = &zoneCache{entries: slices.Clone(entries), fetched: now}
+}
+
+// updateCache applies a change made to the zone to its cached records. A nil
+// update drops them instead, for changes whose outcome is not known.
+func (p *Provider) updateCache(zone string, update func([]godo.DomainRecord) []godo.DomainRecord) {
+	if p.CacheTTL <= 0 {
+		return
+	}
+
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+
+	p.cacheSeq++
+	cached, ok := p.cache[zone]
+	if !ok || update == nil {
+		p.dropCache(zone)
+		p.cacheUncached = p.cacheSeq
+		return
+	}
+
+	if p.cacheGen == nil {
+		p.cacheGen = make(map[string]uint64)
+	}
+	p.cacheGen[zone] = p.cacheSeq
+	cached.entries = update(cached.entries)
+}
+
+// dropCache removes the cached records of the zone along with its last
+// change, which from then on counts as a change to a zone that is not
+// cached. p.cacheMu must be held.
+func (p *Provider) dropCache(zone string) {
+	p.cacheUncached = max(p.cacheUncached, p.cacheGen[zone])
+	delete(p.cache, zone)
+	delete(p.cacheGen, zone)
+}
+
+// cacheAdd, cacheReplace and cacheRemove return updates for updateCache that
+// add, replace or remove a single record.
+func cacheAdd(entry godo.DomainRecord) func([]godo.DomainRecord) []godo.DomainRecord {
+	return func(entries []godo.DomainRecord) []godo.DomainRecord {
+		return append(entries, entry)
+	}
+}
+
+func cacheReplace(entry godo.DomainRecord) func([]godo.DomainRecord) []godo.DomainRecord {
+	return func(entries []godo.DomainRecord) []godo.DomainRecord {
+		for i := range entries {
+			if entries[i].ID == entry.ID {
+				entries[i] = entry
+			}
+		}
+		return entries
+	}
+}
+
+func cacheRemove(id int) func([]godo.DomainRecord) []godo.DomainRecord {
+	return func(entries []godo.DomainRecord) []godo.DomainRecord {
+		return slices.DeleteFunc(entries, func(entry godo.DomainRecord) bool {
+			return entry.ID == id
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+	"github.com/wzzrd/libdns-digitalocean/digitaloceantest"
+)
+
+func TestCache_GetRecords(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+
+	if _, err := server.AddDomain("example.com", godo.DomainRecord{Type: "A", Name: "www", Data: "192.168.1.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Provider{APIToken: "test-token", BaseURL: server.URL, CacheTTL: time.Hour}
+	ctx := context.Background()
+
+	// getRecords returns the number of records in the zone and the number
+	// of requests made to read them
+	getRecords := func(ctx context.Context) (int, int) {
+		t.Helper()
+		before := server.Requests()
+		records, err := p.GetRecords(ctx, "example.com.")
+		if err != nil {
+			t.Fatalf("Provider.GetRecords() error = %v", err)
+		}
+		return len(records), server.Requests() - before
+	}
+
+	if n, requests := getRecords(ctx); n != 5 || requests != 1 {
+		t.Errorf("Provider.GetRecords() = %d records in %d requests, want 5 in 1", n, requests)
+	}
+	if n, requests := getRecords(ctx); n != 5 || requests != 0 {
+		t.Errorf("Provider.GetRecords() = %d records in %d requests, want 5 from the cache", n, requests)
+	}
+
+	// Changes made through the Provider update the cache in place
+	appended, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "A", Name: "mail", Data: "192.168.1.2"},
+	})
+	if err != nil {
+		t.Fatalf("Provider.AppendRecords() error = %v", err)
+	}
+	if _, err := p.updateDNSEntry(ctx, "example.com", withID(libdns.RR{Type: "A", Name: "mail", Data: "192.168.1.3"}, recordID(appended[0]))); err != nil {
+		t.Fatalf("Provider.updateDNSEntry() error = %v", err)
+	}
+	records, err := p.GetRecords(ctx, "example.com.")
+	if err != nil || len(records) != 6 || records[5].RR().Data != "192.168.1.3" {
+		t.Errorf("Provider.GetRecords() = %v, %v, want the edited record from the cache", records, err)
+	}
+
+	// Changes made elsewhere are seen once the cache is bypassed or refreshed
+	if _, err := server.Client().Domains.DeleteRecord(ctx, "example.com", recordID(appended[0])); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := getRecords(ctx); n != 6 {
+		t.Errorf("Provider.GetRecords() = %d records, want the 6 cached ones", n)
+	}
+	if n, requests := getRecords(WithoutCache(ctx)); n != 5 || requests != 1 {
+		t.Errorf("Provider.GetRecords() = %d records in %d requests, want 5 in 1", n, requests)
+	}
+
+	if err := p.RefreshZone(ctx, "example.com."); err != nil {
+		t.Fatalf("Provider.RefreshZone() error = %v", err)
+	}
+	p.InvalidateZone("example.com.")
+	if n, requests := getRecords(ctx); n != 5 || requests != 1 {
+		t.Errorf("Provider.GetRecords() = %d records in %d requests, want 5 in 1 after invalidation", n, requests)
+	}
+}
+
+func TestCache_disabled(t *testing.T) {
+	p := setupTest([]godo.DomainRecord{{ID: 1, Type: "A", Name: "www", Data: "192.168.1.1"}}, nil)
+
+	p.storeEntries("example.com", p.cacheGeneration("example.com"), nil)
+	if _, ok := p.cachedEntries(context.Background(), "example.com"); ok {
+		t.Error("Provider.cachedEntries() returned records with caching disabled")
+	}
+	p.updateCache("example.com", cacheRemove(1))
+	if len(p.cacheGen) != 0 {
+		t.Errorf("Provider.updateCache() tracked changes %v with caching disabled", p.cacheGen)
+	}
+
+	// A change made while the zone is read keeps the outdated records out
+	p.CacheTTL = time.Hour
+	gen := p.cacheGeneration("example.com")
+	p.updateCache("example.com", cacheRemove(1))
+	p.storeEntries("example.com", gen, nil)
+	if _, ok := p.cachedEntries(context.Background(), "example.com"); ok {
+		t.Error("Provider.cachedEntries() returned records read before a change")
+	}
+
+	// Changes to a cached zone are tracked until its records are dropped
+	p.storeEntries("example.com", p.cacheGeneration("example.com"), nil)
+	gen = p.cacheGeneration("example.com")
+	p.updateCache("example.com", cacheRemove(1))
+	if len(p.cacheGen) != 1 {
+		t.Errorf("Provider.updateCache() tracked changes %v, want example.com's", p.cacheGen)
+	}
+	p.storeEntries("example.com", gen, []godo.DomainRecord{{ID: 2}})
+	if cached := p.cache["example.com"]; cached == nil || len(cached.entries) != 0 {
+		t.Error("Provider.storeEntries() replaced records with ones read before a change")
+	}
+	p.InvalidateZone("example.com")
+	if len(p.cache) != 0 || len(p.cacheGen) != 0 {
+		t.Errorf("Provider.InvalidateZone() left %v and %v", p.cache, p.cacheGen)
+	}
+}
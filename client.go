@@ -2,43 +2,371 @@ package digitalocean
 
 import (
 	"context"
+	"fmt"
+	"net/http"
+	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 
 	"github.com/digitalocean/godo"
 	"github.com/libdns/libdns"
+	"golang.org/x/oauth2"
 )
 
+// defaultMaxConcurrency is the number of API calls issued in parallel when
+// Provider.MaxConcurrency is not set.
+const defaultMaxConcurrency = 4
+
+// maxPageSize is the largest number of records DigitalOcean returns in a page.
+const maxPageSize = 200
+
 type Client struct {
-	client *godo.Client
-	mutex  sync.Mutex
+	client    *godo.Client
+	clientErr error
+	once      sync.Once
+
+	// rate is the last rate limit reported by DigitalOcean
+	rateMu sync.Mutex
+	rate   godo.Rate
+
+	// cache holds the records of zones when Provider.CacheTTL is set.
+	// cacheSeq counts the changes made through the Provider, cacheGen holds
+	// the last change made to each cached zone and cacheUncached the last
+	// change made to any other zone
+	cacheMu       sync.Mutex
+	cache         map[string]*zoneCache
+	cacheSeq      uint64
+	cacheGen      map[string]uint64
+	cacheUncached uint64
+
+	// zoneLocks serializes the changes made to a single zone. Locks are
+	// removed once nobody holds or waits for them.
+	zoneLocksMu sync.Mutex
+	zoneLocks   map[string]*zoneLock
 }
 
+// getClient creates the godo client on first use, from the token source and the
+// HTTP client, base URL and user agent options of the Provider. The token
+// source is asked for a token on every request, so rotated tokens are used
+// without creating a new client. An error creating
+// it is returned from every call.
 func (p *Provider) getClient() error {
-	if p.client == nil {
-		p.client = godo.NewFromToken(p.APIToken)
+	p.once.Do(func() {
+		if p.client == nil {
+			p.client, p.clientErr = p.newClient()
+		}
+	})
+
+	return p.clientErr
+}
+
+// newClient creates a godo client. godo.NewFromToken is not used because it
+// enables godo's own retries, which would stack with the ones done by p.do.
+func (p *Provider) newClient() (*godo.Client, error) {
+	ts, err := p.tokenSource()
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	// the HTTP client is copied so the caller's client is left as it is
+	httpClient := &http.Client{}
+	if p.HTTPClient != nil {
+		*httpClient = *p.HTTPClient
+	}
+	httpClient.Transport = &oauth2.Transport{
+		Source: ts,
+		Base:   httpClient.Transport,
+	}
+
+	var opts []godo.ClientOpt
+	if p.BaseURL != "" {
+		baseURL := p.BaseURL
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		opts = append(opts, godo.SetBaseURL(baseURL))
+	}
+
+	client, err := godo.New(httpClient, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("digitalocean: %w", err)
+	}
+	if p.UserAgent != "" {
+		client.UserAgent += " " + p.UserAgent
+	}
+
+	return client, nil
+}
+
+// zoneLock is the lock of a zone, with the number of calls holding or waiting
+// for it.
+type zoneLock struct {
+	sync.Mutex
+	refs int
 }
 
+// lockZone locks the zone for changes and returns the function that unlocks it.
+// Calls changing different zones do not block each other.
+func (p *Provider) lockZone(zone string) func() {
+	p.zoneLocksMu.Lock()
+	if p.zoneLocks == nil {
+		p.zoneLocks = make(map[string]*zoneLock)
+	}
+	lock, ok := p.zoneLocks[zone]
+	if !ok {
+		lock = &zoneLock{}
+		p.zoneLocks[zone] = lock
+	}
+	lock.refs++
+	p.zoneLocksMu.Unlock()
+
+	lock.Lock()
+	return func() {
+		lock.Unlock()
+
+		p.zoneLocksMu.Lock()
+		defer p.zoneLocksMu.Unlock()
+		lock.refs--
+		if lock.refs == 0 {
+			delete(p.zoneLocks, zone)
+		}
+	}
+}
+
+type forEachKey struct{}
+
+// forEach calls fn for every index in [0, n), running at most MaxConcurrency
+// calls in parallel, and waits for all of them to finish. Every index is
+// visited even after ctx is done, so fn can report the cancellation for it.
+// A forEach called from within fn runs its calls one at a time, so nested
+// calls stay within MaxConcurrency as a whole.
+func (p *Provider) forEach(ctx context.Context, n int, fn func(ctx context.Context, i int)) {
+	limit := p.MaxConcurrency
+	if limit <= 0 {
+		limit = defaultMaxConcurrency
+	}
+	if dryRun(ctx) != nil {
+		// nothing is sent in a dry run, so keep the planned changes in order
+		limit = 1
+	}
+	if nested, _ := ctx.Value(forEachKey{}).(bool); nested {
+		limit = 1
+	}
+	ctx = context.WithValue(ctx, forEachKey{}, true)
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			fn(ctx, i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+// getDNSEntries returns the records of the zone, from the cache if caching is
+// enabled and the cached records are fresh.
 func (p *Provider) getDNSEntries(ctx context.Context, zone string) ([]libdns.Record, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
+	entries, ok := p.cachedEntries(ctx, zone)
+	if !ok {
+		gen := p.cacheGeneration(zone)
+
+		var err error
+		entries, err = p.listDNSEntries(ctx, zone)
+		if err != nil {
+			return nil, err
+		}
+
+		p.storeEntries(zone, gen, entries)
+	}
+
+	records := make([]libdns.Record, 0, len(entries))
+	for _, entry := range entries {
+		records = append(records, fromGodo(zone, entry))
+	}
+
+	return records, nil
+}
+
+// listDNSEntries reads all the records of the zone from DigitalOcean.
+func (p *Provider) listDNSEntries(ctx context.Context, zone string) ([]godo.DomainRecord, error) {
+	op := apiOp{method: "Domains.Records", zone: zone}
+	return p.listEntries(ctx, op, func(opt *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error) {
+		return p.client.Domains.Records(ctx, zone, opt)
+	})
+}
+
+// listFilteredEntries reads the records of the zone with the given name, type
+// or both from DigitalOcean, letting the API do the filtering. name is relative
+// to the zone and recType may be empty, but not both.
+func (p *Provider) listFilteredEntries(ctx context.Context, zone, name, recType string) ([]godo.DomainRecord, error) {
+	fqdn := recordFQDN(name, zone)
+
+	op := apiOp{method: "Domains.RecordsByTypeAndName", zone: zone, name: name, recType: recType}
+	switch {
+	case name == "":
+		op.method = "Domains.RecordsByType"
+	case recType == "":
+		op.method = "Domains.RecordsByName"
+	}
+
+	return p.listEntries(ctx, op, func(opt *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error) {
+		switch {
+		case name == "":
+			return p.client.Domains.RecordsByType(ctx, zone, recType, opt)
+		case recType == "":
+			return p.client.Domains.RecordsByName(ctx, zone, fqdn, opt)
+		default:
+			return p.client.Domains.RecordsByTypeAndName(ctx, zone, recType, fqdn, opt)
+		}
+	})
+}
+
+// listEntries calls list for every page of records and returns all of them,
+// logging the calls as op.
+// The first page tells how many pages there are, and the other pages are then
+// fetched in parallel, bounded by MaxConcurrency. Records are de-duplicated by
+// ID, as records added while the zone is listed shift the others to later
+// pages.
+func (p *Provider) listEntries(ctx context.Context, op apiOp, list func(opt *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error)) ([]godo.DomainRecord, error) {
+	if err := p.getClient(); err != nil {
+		return nil, err
+	}
 
-	p.getClient()
+	perPage := p.pageSize()
+	listPage := func(ctx context.Context, page int) ([]godo.DomainRecord, *godo.Response, error) {
+		opt := &godo.ListOptions{Page: page, PerPage: perPage}
+		op := op
+		op.page = page
+		var entries []godo.DomainRecord
+		var resp *godo.Response
+		err := p.do(ctx, &op, func() (*godo.Response, error) {
+			var err error
+			entries, resp, err = list(opt)
+			return resp, err
+		})
+		return entries, resp, err
+	}
+
+	entries, resp, err := listPage(ctx, 1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := 1
+	if !isLastPage(resp) {
+		// when the last page is unknown, fetch the next one and go on from there
+		last := max(lastPage(resp.Links), 2)
+
+		// the first failing page stops the pages that were not requested yet
+		pageCtx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		var errOnce sync.Once
+		var firstErr error
+
+		pages := make([][]godo.DomainRecord, last-1)
+		resps := make([]*godo.Response, last-1)
+		p.forEach(pageCtx, last-1, func(ctx context.Context, i int) {
+			var err error
+			pages[i], resps[i], err = listPage(ctx, i+2)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		})
+		if firstErr != nil {
+			return nil, firstErr
+		}
+		for i := range pages {
+			entries = append(entries, pages[i]...)
+		}
+
+		// records added while listing can push the zone past the last page
+		page, resp = last, resps[last-2]
+		for !isLastPage(resp) {
+			page++
+			var more []godo.DomainRecord
+			more, resp, err = listPage(ctx, page)
+			if err != nil {
+				return nil, err
+			}
+			entries = append(entries, more...)
+		}
+	}
+
+	seen := make(map[int]bool, len(entries))
+	unique := entries[:0]
+	for _, entry := range entries {
+		if !seen[entry.ID] {
+			seen[entry.ID] = true
+			unique = append(unique, entry)
+		}
+	}
+
+	return unique, nil
+}
+
+// pageSize returns the number of records to request per page.
+func (p *Provider) pageSize() int {
+	if p.PageSize <= 0 || p.PageSize > maxPageSize {
+		return maxPageSize
+	}
+	return p.PageSize
+}
+
+// isLastPage reports whether resp holds the last page of a listing.
+func isLastPage(resp *godo.Response) bool {
+	return resp.Links == nil || resp.Links.IsLastPage()
+}
+
+// lastPage returns the number of the last page of a listing, from the link to
+// it, or 0 if there is no such link.
+func lastPage(links *godo.Links) int {
+	if links.Pages == nil || links.Pages.Last == "" {
+		return 0
+	}
+	u, err := url.Parse(links.Pages.Last)
+	if err != nil {
+		return 0
+	}
+	page, err := strconv.Atoi(u.Query().Get("page"))
+	if err != nil {
+		return 0
+	}
+	return page
+}
+
+func (p *Provider) getZones(ctx context.Context) ([]libdns.Zone, error) {
+	if err := p.getClient(); err != nil {
+		return nil, err
+	}
 
 	opt := &godo.ListOptions{}
-	var records []libdns.Record
+	var zones []libdns.Zone
 	for {
-		domains, resp, err := p.client.Domains.Records(ctx, zone, opt)
+		var domains []godo.Domain
+		var resp *godo.Response
+		err := p.do(ctx, &apiOp{method: "Domains.List", page: opt.Page}, func() (*godo.Response, error) {
+			var err error
+			domains, resp, err = p.client.Domains.List(ctx, opt)
+			return resp, err
+		})
 		if err != nil {
-			return records, err
+			return zones, err
 		}
 
-		for _, entry := range domains {
-			record := fromGodo(entry)
-			records = append(records, record)
+		for _, domain := range domains {
+			zones = append(zones, libdns.Zone{Name: domain.Name + "."})
 		}
 
 		// if we are at the last page, break out the for loop
@@ -48,37 +376,55 @@ func (p *Provider) getDNSEntries(ctx context.Context, zone string) ([]libdns.Rec
 
 		page, err := resp.Links.CurrentPage()
 		if err != nil {
-			return records, err
+			return zones, err
 		}
 
 		// set the page we want for the next request
 		opt.Page = page + 1
 	}
 
-	return records, nil
+	return zones, nil
 }
 
 func (p *Provider) addDNSEntry(ctx context.Context, zone string, record libdns.Record) (libdns.Record, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
+	if err := p.getClient(); err != nil {
+		return record, err
+	}
 
-	p.getClient()
+	entry, err := recordToGoDo(zone, record)
+	if err != nil {
+		return record, err
+	}
 
-	entry := recordToGoDo(record)
+	if changes := dryRun(ctx); changes != nil {
+		created := recordFromRequest(zone, entry)
+		changes.add(Change{Op: ChangeCreate, Zone: zone, Record: created, Request: &entry})
+		return created, nil
+	}
 
-	rec, _, err := p.client.Domains.CreateRecord(ctx, zone, &entry)
+	op := &apiOp{method: "Domains.CreateRecord", zone: zone, name: entry.Name, recType: entry.Type, data: entry.Data, create: true}
+	var rec *godo.DomainRecord
+	err = p.do(ctx, op, func() (*godo.Response, error) {
+		var resp *godo.Response
+		rec, resp, err = p.client.Domains.CreateRecord(ctx, zone, &entry)
+		if rec != nil {
+			op.id = rec.ID
+		}
+		return resp, err
+	})
 	if err != nil {
+		p.updateCache(zone, nil)
 		return record, err
 	}
+	p.updateCache(zone, cacheAdd(*rec))
 
-	return fromRecord(record, strconv.Itoa(rec.ID)), nil
+	return fromGodo(zone, *rec), nil
 }
 
 func (p *Provider) removeDNSEntry(ctx context.Context, zone string, record libdns.Record) (libdns.Record, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
-	p.getClient()
+	if err := p.getClient(); err != nil {
+		return record, err
+	}
 
 	// Get ID from dns record
 	id, err := idFromRecord(record)
@@ -86,19 +432,29 @@ func (p *Provider) removeDNSEntry(ctx context.Context, zone string, record libdn
 		return record, err
 	}
 
-	_, err = p.client.Domains.DeleteRecord(ctx, zone, id)
+	if changes := dryRun(ctx); changes != nil {
+		changes.add(Change{Op: ChangeDelete, Zone: zone, ID: id, Record: record})
+		return record, nil
+	}
+
+	rr := record.RR()
+	op := &apiOp{method: "Domains.DeleteRecord", zone: zone, name: rr.Name, recType: rr.Type, id: id, remove: true}
+	err = p.do(ctx, op, func() (*godo.Response, error) {
+		return p.client.Domains.DeleteRecord(ctx, zone, id)
+	})
 	if err != nil {
+		p.updateCache(zone, nil)
 		return record, err
 	}
+	p.updateCache(zone, cacheRemove(id))
 
 	return record, nil
 }
 
 func (p *Provider) updateDNSEntry(ctx context.Context, zone string, record libdns.Record) (libdns.Record, error) {
-	p.mutex.Lock()
-	defer p.mutex.Unlock()
-
-	p.getClient()
+	if err := p.getClient(); err != nil {
+		return record, err
+	}
 
 	// Get ID from dns record
 	id, err := idFromRecord(record)
@@ -106,12 +462,29 @@ func (p *Provider) updateDNSEntry(ctx context.Context, zone string, record libdn
 		return record, err
 	}
 
-	entry := recordToGoDo(record)
+	entry, err := recordToGoDo(zone, record)
+	if err != nil {
+		return record, err
+	}
 
-	_, _, err = p.client.Domains.EditRecord(ctx, zone, id, &entry)
+	if changes := dryRun(ctx); changes != nil {
+		updated := withID(recordFromRequest(zone, entry), id)
+		changes.add(Change{Op: ChangeUpdate, Zone: zone, ID: id, Record: updated, Request: &entry})
+		return updated, nil
+	}
+
+	op := &apiOp{method: "Domains.EditRecord", zone: zone, name: entry.Name, recType: entry.Type, id: id, data: entry.Data}
+	var rec *godo.DomainRecord
+	err = p.do(ctx, op, func() (*godo.Response, error) {
+		var resp *godo.Response
+		rec, resp, err = p.client.Domains.EditRecord(ctx, zone, id, &entry)
+		return resp, err
+	})
 	if err != nil {
+		p.updateCache(zone, nil)
 		return record, err
 	}
+	p.updateCache(zone, cacheReplace(*rec))
 
-	return record, nil
+	return fromGodo(zone, *rec), nil
 }
@@ -3,11 +3,18 @@ package digitalocean
 import (
 	"context"
 	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/digitalocean/godo"
 	"github.com/libdns/libdns"
+	"github.com/wzzrd/libdns-digitalocean/digitaloceantest"
 )
 
 func TestClient_getDNSEntries(t *testing.T) {
@@ -43,15 +50,23 @@ func TestClient_getDNSEntries(t *testing.T) {
 		t.Errorf("Client.getDNSEntries() returned %d records, want 2", len(records))
 	}
 
+	// Verify records are returned as typed libdns records
+	if _, ok := records[0].(libdns.Address); !ok {
+		t.Errorf("Client.getDNSEntries()[0] is %T, want libdns.Address", records[0])
+	}
+	if _, ok := records[1].(libdns.CNAME); !ok {
+		t.Errorf("Client.getDNSEntries()[1] is %T, want libdns.CNAME", records[1])
+	}
+
 	// Verify first record
 	if records[0].RR().Type != "A" || records[0].RR().Name != "test" ||
-		records[0].RR().Data != "192.168.1.1" || records[0].(DNS).ID != "1" {
+		records[0].RR().Data != "192.168.1.1" || recordID(records[0]) != 1 {
 		t.Errorf("Client.getDNSEntries()[0] = %v, want A record", records[0])
 	}
 
 	// Verify second record
 	if records[1].RR().Type != "CNAME" || records[1].RR().Name != "www" ||
-		records[1].RR().Data != "example.com" || records[1].(DNS).ID != "2" {
+		records[1].RR().Data != "example.com." || recordID(records[1]) != 2 {
 		t.Errorf("Client.getDNSEntries()[1] = %v, want CNAME record", records[1])
 	}
 
@@ -87,7 +102,7 @@ func TestClient_addDNSEntry(t *testing.T) {
 	if resultRecord.RR().Type != testRecord.Type ||
 		resultRecord.RR().Name != testRecord.Name ||
 		resultRecord.RR().Data != testRecord.Data ||
-		resultRecord.(DNS).ID != "12345" {
+		recordID(resultRecord) != 12345 {
 		t.Errorf("Client.addDNSEntry() record mismatch, got = %v, want Type=%s, Name=%s, Data=%s, ID=12345",
 			resultRecord, testRecord.RR().Type, testRecord.RR().Name, testRecord.RR().Data)
 	}
@@ -175,7 +190,8 @@ func TestClient_updateDNSEntry(t *testing.T) {
 	}
 
 	// Verify the record was preserved
-	if resultRecord.(DNS).ID != testRecord.ID ||
+	if _, ok := resultRecord.(libdns.Address); !ok ||
+		recordID(resultRecord) != 1 ||
 		resultRecord.RR().Type != testRecord.RR().Type ||
 		resultRecord.RR().Name != testRecord.RR().Name ||
 		resultRecord.RR().Data != testRecord.RR().Data {
@@ -206,3 +222,216 @@ func TestClient_updateDNSEntry(t *testing.T) {
 		t.Error("Client.updateDNSEntry() expected error for invalid ID, got nil")
 	}
 }
+
+func TestClient_forEach(t *testing.T) {
+	p := &Provider{MaxConcurrency: 3}
+	ctx := context.Background()
+
+	// Verify the number of parallel calls stays within MaxConcurrency
+	var mu sync.Mutex
+	running, peak, calls := 0, 0, 0
+
+	count := func(ctx context.Context, i int) {
+		mu.Lock()
+		running++
+		calls++
+		if running > peak {
+			peak = running
+		}
+		mu.Unlock()
+
+		time.Sleep(time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+	p.forEach(ctx, 10, count)
+
+	// Nested calls stay within MaxConcurrency as a whole
+	p.forEach(ctx, 5, func(ctx context.Context, i int) {
+		p.forEach(ctx, 2, count)
+	})
+
+	if calls != 20 {
+		t.Errorf("Client.forEach() made %d calls, want 20", calls)
+	}
+	if peak > 3 {
+		t.Errorf("Client.forEach() ran %d calls in parallel, want at most 3", peak)
+	}
+}
+
+func TestClient_lockZone(t *testing.T) {
+	p := &Provider{}
+
+	unlock := p.lockZone("example.com")
+	done := make(chan struct{})
+	go func() {
+		p.lockZone("example.com")()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("Provider.lockZone() did not block while the zone was locked")
+	case <-time.After(10 * time.Millisecond):
+	}
+	unlock()
+	<-done
+
+	// Locks nobody holds are removed
+	if len(p.zoneLocks) != 0 {
+		t.Errorf("Provider.lockZone() left %d locks behind", len(p.zoneLocks))
+	}
+}
+
+func TestClient_listEntries(t *testing.T) {
+	p := &Provider{PageSize: 2, Client: Client{client: &godo.Client{}}}
+	ctx := context.Background()
+
+	// Four pages are listed; a record added during the listing shifts record 6
+	// onto page 4 and record 7 onto a fifth page the first page did not know of
+	pages := map[int][]int{1: {1, 2}, 2: {3, 4}, 3: {5, 6}, 4: {6, 7}, 5: {8}}
+	var mu sync.Mutex
+	var requested []int
+	list := func(opt *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error) {
+		mu.Lock()
+		requested = append(requested, opt.Page)
+		mu.Unlock()
+
+		if opt.PerPage != 2 {
+			t.Errorf("page %d requested with PerPage %d, want 2", opt.Page, opt.PerPage)
+		}
+		var entries []godo.DomainRecord
+		for _, id := range pages[opt.Page] {
+			entries = append(entries, godo.DomainRecord{ID: id, Type: "A", Name: "test", Data: "192.168.1.1"})
+		}
+		links := &godo.Links{Pages: &godo.Pages{}}
+		if opt.Page == 1 {
+			links.Pages.Last = "https://api.digitalocean.com/v2/domains/example.com/records?page=4&per_page=2"
+		}
+		if opt.Page < 5 {
+			links.Pages.Next = fmt.Sprintf("https://api.digitalocean.com/v2/domains/example.com/records?page=%d&per_page=2", opt.Page+1)
+		}
+		return entries, &godo.Response{Links: links}, nil
+	}
+
+	entries, err := p.listEntries(ctx, apiOp{method: "Test"}, list)
+	if err != nil {
+		t.Fatalf("Client.listEntries() error = %v", err)
+	}
+	var ids []int
+	for _, entry := range entries {
+		ids = append(ids, entry.ID)
+	}
+	if got := fmt.Sprint(ids); got != "[1 2 3 4 5 6 7 8]" {
+		t.Errorf("Client.listEntries() returned records %s, want [1 2 3 4 5 6 7 8]", got)
+	}
+	sort.Ints(requested)
+	if got := fmt.Sprint(requested); got != "[1 2 3 4 5]" {
+		t.Errorf("Client.listEntries() requested pages %s, want [1 2 3 4 5]", got)
+	}
+
+	// Test error case: a failing page stops the pages that were not requested yet
+	p.MaxConcurrency = 1
+	failing := func(opt *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error) {
+		if opt.Page == 2 {
+			mu.Lock()
+			requested = append(requested, opt.Page)
+			mu.Unlock()
+			return nil, &godo.Response{Response: &http.Response{StatusCode: http.StatusNotFound}}, errors.New("not found")
+		}
+		return list(opt)
+	}
+	requested = nil
+	if _, err := p.listEntries(ctx, apiOp{method: "Test"}, failing); err == nil || err.Error() != "not found" {
+		t.Errorf("Client.listEntries() with a failing page error = %v, want not found", err)
+	}
+	if got := fmt.Sprint(requested); got != "[1 2]" {
+		t.Errorf("Client.listEntries() requested pages %s after page 2 failed, want [1 2]", got)
+	}
+}
+
+func TestClient_listEntriesFakeServer(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+
+	var records []godo.DomainRecord
+	for i := 0; i < 446; i++ {
+		records = append(records, godo.DomainRecord{Type: "A", Name: fmt.Sprintf("host%d", i), Data: "192.168.1.1", TTL: 3600})
+	}
+	if _, err := server.AddDomain("example.com", records...); err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+
+	// The zone holds 450 records with the SOA and NS records, three pages of 200
+	p := &Provider{APIToken: "test-token", BaseURL: server.URL}
+	entries, err := p.listDNSEntries(context.Background(), "example.com")
+	if err != nil {
+		t.Fatalf("Client.listDNSEntries() error = %v", err)
+	}
+	if len(entries) != 450 {
+		t.Errorf("Client.listDNSEntries() returned %d records, want 450", len(entries))
+	}
+	if server.Requests() != 3 {
+		t.Errorf("Client.listDNSEntries() made %d requests, want 3", server.Requests())
+	}
+}
+
+// countingTransport counts the requests sent through it
+type countingTransport struct {
+	requests int
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	t.requests++
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestClient_getClient(t *testing.T) {
+	var gotPath, gotAuth, gotUserAgent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotAuth, gotUserAgent = r.URL.Path, r.Header.Get("Authorization"), r.Header.Get("User-Agent")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"domains": [{"name": "example.com"}]}`))
+	}))
+	defer server.Close()
+
+	transport := &countingTransport{}
+	p := &Provider{
+		APIToken:   "test-token",
+		HTTPClient: &http.Client{Transport: transport, Timeout: 5 * time.Second},
+		BaseURL:    server.URL + "/proxy",
+		UserAgent:  "my-app/1.0",
+	}
+
+	zones, err := p.ListZones(context.Background())
+	if err != nil {
+		t.Fatalf("Provider.ListZones() error = %v", err)
+	}
+	if len(zones) != 1 || zones[0].Name != "example.com." {
+		t.Errorf("Provider.ListZones() = %v, want example.com.", zones)
+	}
+
+	if gotPath != "/proxy/v2/domains" {
+		t.Errorf("request path = %q, want /proxy/v2/domains", gotPath)
+	}
+	if gotAuth != "Bearer test-token" {
+		t.Errorf("Authorization header = %q, want the API token", gotAuth)
+	}
+	if !strings.HasPrefix(gotUserAgent, "godo/") || !strings.HasSuffix(gotUserAgent, " my-app/1.0") {
+		t.Errorf("User-Agent header = %q, want godo's followed by my-app/1.0", gotUserAgent)
+	}
+	if transport.requests != 1 {
+		t.Errorf("custom transport saw %d requests, want 1", transport.requests)
+	}
+	if p.HTTPClient.Transport != transport {
+		t.Error("Provider.HTTPClient was modified")
+	}
+
+	// Test error case
+	p = &Provider{}
+	if _, err := p.GetRecords(context.Background(), "example.com."); err == nil {
+		t.Error("Provider.GetRecords() without token expected error, got nil")
+	}
+}
@@ -0,0 +1,126 @@
+package digitalocean
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// duration is a time.Duration that reads from JSON as a string such as "5m"
+// or "1h30m", or as a number of seconds, and is written as a string.
+type duration time.Duration
+
+func (d *duration) UnmarshalJSON(b []byte) error {
+	var v any
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case float64:
+		*d = duration(v * float64(time.Second))
+		return nil
+	case string:
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil {
+			*d = duration(seconds * float64(time.Second))
+			return nil
+		}
+		parsed, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q: %w", v, err)
+		}
+		*d = duration(parsed)
+		return nil
+	default:
+		return fmt.Errorf("invalid duration %s: must be a string such as \"5m\" or a number of seconds", b)
+	}
+}
+
+func (d duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// plainProvider has the fields of Provider without its JSON methods.
+type plainProvider Provider
+
+// providerJSON is the JSON form of a Provider. Its duration fields shadow the
+// time.Duration fields of the embedded Provider.
+type providerJSON struct {
+	*plainProvider
+	TokenCommandRefresh duration `json:"token_command_refresh,omitempty"`
+	TokenCommandTimeout duration `json:"token_command_timeout,omitempty"`
+	RetryMinWait        duration `json:"retry_min_wait,omitempty"`
+	RetryMaxWait        duration `json:"retry_max_wait,omitempty"`
+	CacheTTL            duration `json:"cache_ttl,omitempty"`
+}
+
+func newProviderJSON(p *Provider) providerJSON {
+	return providerJSON{
+		plainProvider:       (*plainProvider)(p),
+		TokenCommandRefresh: duration(p.TokenCommandRefresh),
+		TokenCommandTimeout: duration(p.TokenCommandTimeout),
+		RetryMinWait:        duration(p.RetryMinWait),
+		RetryMaxWait:        duration(p.RetryMaxWait),
+		CacheTTL:            duration(p.CacheTTL),
+	}
+}
+
+// UnmarshalJSON reads the configuration of the Provider. Durations are given
+// as strings such as "5m" or as a number of seconds.
+func (p *Provider) UnmarshalJSON(b []byte) error {
+	aux := newProviderJSON(p)
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+
+	p.TokenCommandRefresh = time.Duration(aux.TokenCommandRefresh)
+	p.TokenCommandTimeout = time.Duration(aux.TokenCommandTimeout)
+	p.RetryMinWait = time.Duration(aux.RetryMinWait)
+	p.RetryMaxWait = time.Duration(aux.RetryMaxWait)
+	p.CacheTTL = time.Duration(aux.CacheTTL)
+	return nil
+}
+
+// MarshalJSON writes the configuration of the Provider, with durations as
+// strings such as "5m0s".
+func (p *Provider) MarshalJSON() ([]byte, error) {
+	return json.Marshal(newProviderJSON(p))
+}
+
+// UnmarshalJSON reads the configuration of the Account: the settings of its
+// Provider and its zones.
+func (a *Account) UnmarshalJSON(b []byte) error {
+	if err := a.Provider.UnmarshalJSON(b); err != nil {
+		return err
+	}
+
+	aux := struct {
+		Zones []string `json:"zones"`
+	}{Zones: a.Zones}
+	if err := json.Unmarshal(b, &aux); err != nil {
+		return err
+	}
+	a.Zones = aux.Zones
+	return nil
+}
+
+// MarshalJSON writes the configuration of the Account: the settings of its
+// Provider and its zones.
+func (a *Account) MarshalJSON() ([]byte, error) {
+	b, err := a.Provider.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		return nil, err
+	}
+	if fields["zones"], err = json.Marshal(a.Zones); err != nil {
+		return nil, err
+	}
+	return json.Marshal(fields)
+}
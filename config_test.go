@@ -0,0 +1,107 @@
+package digitalocean
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestConfig_duration(t *testing.T) {
+	tests := []struct {
+		json    string
+		want    time.Duration
+		wantErr bool
+	}{
+		{json: `"5m"`, want: 5 * time.Minute},
+		{json: `"1h30m"`, want: 90 * time.Minute},
+		{json: `30`, want: 30 * time.Second},
+		{json: `1.5`, want: 1500 * time.Millisecond},
+		{json: `"45"`, want: 45 * time.Second},
+		{json: `"soon"`, wantErr: true},
+		{json: `true`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.json, func(t *testing.T) {
+			var got duration
+			err := json.Unmarshal([]byte(tt.json), &got)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("json.Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && time.Duration(got) != tt.want {
+				t.Errorf("json.Unmarshal() = %s, want %s", time.Duration(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Provider(t *testing.T) {
+	config := `{
+		"auth_token": "secret",
+		"token_command_refresh": "10m",
+		"token_command_timeout": 5,
+		"retry_min_wait": "500ms",
+		"retry_max_wait": "1m",
+		"cache_ttl": 300,
+		"max_retries": 3
+	}`
+	p := Provider{RetryMaxWait: time.Hour}
+	if err := json.Unmarshal([]byte(config), &p); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	if p.APIToken != "secret" || p.MaxRetries != 3 {
+		t.Errorf("json.Unmarshal() token = %q, retries = %d, want secret and 3", p.APIToken, p.MaxRetries)
+	}
+	if p.TokenCommandRefresh != 10*time.Minute || p.TokenCommandTimeout != 5*time.Second {
+		t.Errorf("json.Unmarshal() token command refresh = %s, timeout = %s, want 10m and 5s", p.TokenCommandRefresh, p.TokenCommandTimeout)
+	}
+	if p.RetryMinWait != 500*time.Millisecond || p.RetryMaxWait != time.Minute {
+		t.Errorf("json.Unmarshal() retry waits = %s, %s, want 500ms and 1m", p.RetryMinWait, p.RetryMaxWait)
+	}
+	if p.CacheTTL != 5*time.Minute {
+		t.Errorf("json.Unmarshal() cache TTL = %s, want 5m", p.CacheTTL)
+	}
+
+	if err := json.Unmarshal([]byte(`{"cache_ttl": "later"}`), &p); err == nil {
+		t.Error("json.Unmarshal() with an invalid duration expected error, got nil")
+	}
+
+	// Durations are written back in a form they are read from
+	b, err := json.Marshal(&p)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var again Provider
+	if err := json.Unmarshal(b, &again); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", b, err)
+	}
+	if again.CacheTTL != p.CacheTTL || again.RetryMinWait != p.RetryMinWait || again.APIToken != p.APIToken {
+		t.Errorf("json.Unmarshal(%s) cache TTL = %s, retry min wait = %s, want %s and %s", b, again.CacheTTL, again.RetryMinWait, p.CacheTTL, p.RetryMinWait)
+	}
+}
+
+func TestConfig_Account(t *testing.T) {
+	var r Router
+	config := `{"accounts": [{"auth_token": "secret", "cache_ttl": "1m", "zones": ["example.com"]}]}`
+	if err := json.Unmarshal([]byte(config), &r); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+
+	account := r.Accounts[0]
+	if account.APIToken != "secret" || account.CacheTTL != time.Minute || len(account.Zones) != 1 || account.Zones[0] != "example.com" {
+		t.Errorf("json.Unmarshal() = %+v, want token, cache TTL and zone", account)
+	}
+
+	b, err := json.Marshal(&r)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	var again Router
+	if err := json.Unmarshal(b, &again); err != nil {
+		t.Fatalf("json.Unmarshal(%s) error = %v", b, err)
+	}
+	if got := again.Accounts[0]; got.CacheTTL != time.Minute || len(got.Zones) != 1 || got.Zones[0] != "example.com" {
+		t.Errorf("json.Unmarshal(%s) = %+v, want cache TTL and zone", b, got)
+	}
+}
@@ -0,0 +1,650 @@
+// Package digitaloceantest provides an in-process fake of the DigitalOcean
+// domains API, for testing code that uses github.com/wzzrd/libdns-digitalocean
+// or godo without network access.
+//
+// The fake keeps state like the real API does: domains get DigitalOcean's SOA
+// and NS records when they are created, records get IDs, lists are paginated
+// with links.pages and requests are validated the way DigitalOcean validates
+// them. Errors such as 429 Too Many Requests can be injected with Fail.
+package digitaloceantest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+const (
+	// defaultTTL is the TTL DigitalOcean gives records created without one
+	defaultTTL = 1800
+	// minTTL is the lowest TTL DigitalOcean accepts
+	minTTL = 30
+	// defaultPerPage and maxPerPage bound the size of a page of results
+	defaultPerPage = 20
+	maxPerPage     = 200
+	// rateLimit is the number of requests allowed per hour
+	rateLimit = 5000
+)
+
+// Server is a fake DigitalOcean API server. Create one with NewServer and
+// close it when done.
+type Server struct {
+	*httptest.Server
+
+	// Token is the API token requests must carry. Any token is accepted if
+	// it is empty.
+	Token string
+
+	mu       sync.Mutex
+	domains  map[string][]godo.DomainRecord
+	nextID   int
+	requests int
+	// window counts the requests since the rate limit was last reset
+	window   int
+	failures []int
+	reset    time.Time
+}
+
+// NewServer starts a fake DigitalOcean API server without any domains.
+func NewServer() *Server {
+	s := &Server{
+		domains: make(map[string][]godo.DomainRecord),
+		nextID:  1,
+		reset:   time.Now().Add(time.Hour),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/domains", s.listDomains)
+	mux.HandleFunc("POST /v2/domains", s.createDomain)
+	mux.HandleFunc("GET /v2/domains/{domain}", s.getDomain)
+	mux.HandleFunc("DELETE /v2/domains/{domain}", s.deleteDomain)
+	mux.HandleFunc("GET /v2/domains/{domain}/records", s.listRecords)
+	mux.HandleFunc("POST /v2/domains/{domain}/records", s.createRecord)
+	mux.HandleFunc("GET /v2/domains/{domain}/records/{id}", s.getRecord)
+	mux.HandleFunc("PUT /v2/domains/{domain}/records/{id}", s.editRecord)
+	mux.HandleFunc("PATCH /v2/domains/{domain}/records/{id}", s.editRecord)
+	mux.HandleFunc("DELETE /v2/domains/{domain}/records/{id}", s.deleteRecord)
+
+	s.Server = httptest.NewServer(s.middleware(mux))
+	return s
+}
+
+// Client returns a godo client that talks to the server, authenticating with
+// Token. Set Token before calling Client.
+func (s *Server) Client() *godo.Client {
+	client := godo.NewClient(&http.Client{Transport: &tokenTransport{token: s.Token}})
+	client.BaseURL, _ = url.Parse(s.URL + "/")
+	return client
+}
+
+// AddDomain creates a domain with DigitalOcean's SOA and NS records, and adds
+// records to it. It returns the added records with their IDs.
+func (s *Server) AddDomain(name string, records ...godo.DomainRecord) ([]godo.DomainRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.domains[name]; ok {
+		return nil, fmt.Errorf("domain %s already exists", name)
+	}
+	s.addDomain(name)
+
+	var added []godo.DomainRecord
+	for _, record := range records {
+		req := godo.DomainRecordEditRequest{
+			Type: record.Type, Name: record.Name, Data: record.Data, Priority: record.Priority,
+			Port: record.Port, TTL: record.TTL, Weight: record.Weight, Flags: record.Flags, Tag: record.Tag,
+		}
+		if err := s.validate(name, 0, &req); err != nil {
+			return added, err
+		}
+		added = append(added, s.addRecord(name, req))
+	}
+
+	return added, nil
+}
+
+// Records returns the records of a domain, or nil if it does not exist.
+func (s *Server) Records(domain string) []godo.DomainRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return slices.Clone(s.domains[domain])
+}
+
+// Requests returns the number of requests the server has received.
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// Fail makes the next n requests fail with the given status code, without
+// being handled. A 429 Too Many Requests status reports an exhausted rate
+// limit.
+func (s *Server) Fail(status, n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := 0; i < n; i++ {
+		s.failures = append(s.failures, status)
+	}
+}
+
+// middleware counts requests, checks the token, sets the rate limit and
+// request ID headers and injects the failures queued by Fail.
+func (s *Server) middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests++
+		requestID := strconv.Itoa(s.requests)
+		if now := time.Now(); now.After(s.reset) {
+			s.reset = now.Add(time.Hour)
+			s.window = 0
+		}
+		s.window++
+		remaining := max(rateLimit-s.window, 0)
+		reset := s.reset
+		status := 0
+		if len(s.failures) > 0 {
+			status, s.failures = s.failures[0], s.failures[1:]
+		}
+		s.mu.Unlock()
+
+		w.Header().Set("x-request-id", requestID)
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(rateLimit))
+
+		if status == http.StatusTooManyRequests {
+			remaining, reset = 0, time.Now()
+		}
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+		if auth := r.Header.Get("Authorization"); auth == "" || (s.Token != "" && auth != "Bearer "+s.Token) {
+			writeError(w, http.StatusUnauthorized, "unauthorized", "Unable to authenticate you")
+			return
+		}
+		if status != 0 {
+			writeError(w, status, "injected_error", http.StatusText(status))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) listDomains(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	names := make([]string, 0, len(s.domains))
+	for name := range s.domains {
+		names = append(names, name)
+	}
+	s.mu.Unlock()
+	slices.Sort(names)
+
+	start, end, links, ok := s.paginate(w, r, len(names))
+	if !ok {
+		return
+	}
+
+	domains := make([]godo.Domain, 0, end-start)
+	for _, name := range names[start:end] {
+		domains = append(domains, godo.Domain{Name: name, TTL: defaultTTL})
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"domains": domains,
+		"links":   links,
+		"meta":    godo.Meta{Total: len(names)},
+	})
+}
+
+func (s *Server) createDomain(w http.ResponseWriter, r *http.Request) {
+	var req godo.DomainCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+	if req.IPAddress != "" && net.ParseIP(req.IPAddress) == nil {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Ip address is invalid")
+		return
+	}
+	if req.Name == "" || strings.HasSuffix(req.Name, ".") || !strings.Contains(req.Name, ".") {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Name is invalid")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.domains[req.Name]; ok {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Name already exists")
+		return
+	}
+	s.addDomain(req.Name)
+	if req.IPAddress != "" {
+		recordType := "A"
+		if net.ParseIP(req.IPAddress).To4() == nil {
+			recordType = "AAAA"
+		}
+		s.addRecord(req.Name, godo.DomainRecordEditRequest{Type: recordType, Name: "@", Data: req.IPAddress, TTL: defaultTTL})
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"domain": godo.Domain{Name: req.Name, TTL: defaultTTL}})
+}
+
+func (s *Server) getDomain(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	_, ok := s.domains[r.PathValue("domain")]
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "The resource you were accessing could not be found.")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"domain": godo.Domain{Name: r.PathValue("domain"), TTL: defaultTTL}})
+}
+
+func (s *Server) deleteDomain(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domain := r.PathValue("domain")
+	if _, ok := s.domains[domain]; !ok {
+		writeError(w, http.StatusNotFound, "not_found", "The resource you were accessing could not be found.")
+		return
+	}
+	delete(s.domains, domain)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listRecords(w http.ResponseWriter, r *http.Request) {
+	domain := r.PathValue("domain")
+	name, recordType := r.URL.Query().Get("name"), r.URL.Query().Get("type")
+
+	s.mu.Lock()
+	all, ok := s.domains[domain]
+	var records []godo.DomainRecord
+	for _, record := range all {
+		if name != "" && !strings.EqualFold(fqdn(record.Name, domain), strings.TrimSuffix(name, ".")) {
+			continue
+		}
+		if recordType != "" && record.Type != recordType {
+			continue
+		}
+		records = append(records, record)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found", "The resource you were accessing could not be found.")
+		return
+	}
+
+	start, end, links, ok := s.paginate(w, r, len(records))
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{
+		"domain_records": append([]godo.DomainRecord{}, records[start:end]...),
+		"links":          links,
+		"meta":           godo.Meta{Total: len(records)},
+	})
+}
+
+func (s *Server) createRecord(w http.ResponseWriter, r *http.Request) {
+	var req godo.DomainRecordEditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	domain := r.PathValue("domain")
+	if _, ok := s.domains[domain]; !ok {
+		writeError(w, http.StatusNotFound, "not_found", "The resource you were accessing could not be found.")
+		return
+	}
+	if err := s.validate(domain, 0, &req); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", err.Error())
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]any{"domain_record": s.addRecord(domain, req)})
+}
+
+func (s *Server) getRecord(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.findRecord(w, r)
+	if !ok {
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"domain_record": s.domains[r.PathValue("domain")][i]})
+}
+
+func (s *Server) editRecord(w http.ResponseWriter, r *http.Request) {
+	var req godo.DomainRecordEditRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad_request", err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.findRecord(w, r)
+	if !ok {
+		return
+	}
+
+	domain := r.PathValue("domain")
+	record := &s.domains[domain][i]
+	if record.Type == "SOA" && req.Type != "" && req.Type != "SOA" {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Type cannot be changed")
+		return
+	}
+
+	// fields missing from the request keep their current value
+	if req.Type == "" {
+		req.Type = record.Type
+	}
+	if req.Name == "" {
+		req.Name = record.Name
+	}
+	if req.Data == "" {
+		req.Data = record.Data
+		// DigitalOcean's own NS records hold names without a trailing dot, which
+		// the request is not to blame for
+		if req.Type == record.Type && hasHostname(req.Type) && req.Data != "@" && !strings.HasSuffix(req.Data, ".") {
+			req.Data += "."
+		}
+	}
+	if req.TTL == 0 {
+		req.TTL = record.TTL
+	}
+	if err := s.validate(domain, record.ID, &req); err != nil {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", err.Error())
+		return
+	}
+
+	*record = godo.DomainRecord{
+		ID: record.ID, Type: req.Type, Name: req.Name, Data: req.Data, Priority: req.Priority,
+		Port: req.Port, TTL: req.TTL, Weight: req.Weight, Flags: req.Flags, Tag: req.Tag,
+	}
+
+	writeJSON(w, http.StatusOK, map[string]any{"domain_record": *record})
+}
+
+func (s *Server) deleteRecord(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, ok := s.findRecord(w, r)
+	if !ok {
+		return
+	}
+
+	domain := r.PathValue("domain")
+	if record := s.domains[domain][i]; record.Type == "SOA" || (record.Type == "NS" && record.Name == "@" && strings.HasSuffix(record.Data, ".digitalocean.com")) {
+		writeError(w, http.StatusUnprocessableEntity, "unprocessable_entity", "Record is managed by DigitalOcean and cannot be deleted")
+		return
+	}
+	s.domains[domain] = slices.Delete(s.domains[domain], i, i+1)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// findRecord returns the index of the record a request refers to. It writes a
+// 404 response if there is no such record. s.mu must be held.
+func (s *Server) findRecord(w http.ResponseWriter, r *http.Request) (int, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err == nil {
+		for i, record := range s.domains[r.PathValue("domain")] {
+			if record.ID == id {
+				return i, true
+			}
+		}
+	}
+
+	writeError(w, http.StatusNotFound, "not_found", "The resource you were accessing could not be found.")
+	return 0, false
+}
+
+// addDomain creates an empty domain with DigitalOcean's SOA and NS records.
+// Like DigitalOcean, it stores the NS names without a trailing dot; edits
+// that keep them add the dot instead of failing validation. s.mu must be held.
+func (s *Server) addDomain(name string) {
+	s.domains[name] = nil
+	s.addRecord(name, godo.DomainRecordEditRequest{Type: "SOA", Name: "@", Data: "1800", TTL: defaultTTL})
+	for i := 1; i <= 3; i++ {
+		s.addRecord(name, godo.DomainRecordEditRequest{Type: "NS", Name: "@", Data: fmt.Sprintf("ns%d.digitalocean.com", i), TTL: defaultTTL})
+	}
+}
+
+// addRecord adds a record to a domain and assigns it an ID. s.mu must be held.
+func (s *Server) addRecord(domain string, req godo.DomainRecordEditRequest) godo.DomainRecord {
+	record := godo.DomainRecord{
+		ID: s.nextID, Type: req.Type, Name: req.Name, Data: req.Data, Priority: req.Priority,
+		Port: req.Port, TTL: req.TTL, Weight: req.Weight, Flags: req.Flags, Tag: req.Tag,
+	}
+	s.nextID++
+	s.domains[domain] = append(s.domains[domain], record)
+	return record
+}
+
+// validate checks a record create or edit request for domain the way
+// DigitalOcean does, and fills in defaults. id is the ID of the edited record,
+// or 0 for a new one. s.mu must be held.
+func (s *Server) validate(domain string, id int, req *godo.DomainRecordEditRequest) error {
+	if req.Name == domain || req.Name == domain+"." {
+		req.Name = "@"
+	}
+	if req.TTL == 0 {
+		req.TTL = defaultTTL
+	}
+
+	switch {
+	case req.Type == "":
+		return fmt.Errorf("Type can't be blank")
+	case req.Name == "":
+		return fmt.Errorf("Name can't be blank")
+	case req.Data == "":
+		return fmt.Errorf("Data can't be blank")
+	case req.TTL < minTTL:
+		return fmt.Errorf("Ttl must be greater than or equal to %d", minTTL)
+	}
+
+	switch req.Type {
+	case "A":
+		if ip := net.ParseIP(req.Data); ip == nil || ip.To4() == nil {
+			return fmt.Errorf("Data must be a valid IPv4 address")
+		}
+	case "AAAA":
+		if ip := net.ParseIP(req.Data); ip == nil || ip.To4() != nil {
+			return fmt.Errorf("Data must be a valid IPv6 address")
+		}
+	case "CNAME":
+		if req.Name == "@" {
+			return fmt.Errorf("CNAME records cannot be created at the zone apex")
+		}
+		if err := validateHostname(req.Data); err != nil {
+			return err
+		}
+	case "MX":
+		if req.Priority < 0 || req.Priority > 65535 {
+			return fmt.Errorf("Priority must be between 0 and 65535")
+		}
+		if err := validateHostname(req.Data); err != nil {
+			return err
+		}
+	case "NS":
+		if err := validateHostname(req.Data); err != nil {
+			return err
+		}
+	case "SRV":
+		if !strings.HasPrefix(req.Name, "_") {
+			return fmt.Errorf("Name must be in the form _service._protocol")
+		}
+		if req.Priority < 0 || req.Priority > 65535 || req.Weight < 0 || req.Weight > 65535 {
+			return fmt.Errorf("Priority and weight must be between 0 and 65535")
+		}
+		if req.Port < 1 || req.Port > 65535 {
+			return fmt.Errorf("Port must be between 1 and 65535")
+		}
+		if err := validateHostname(req.Data); err != nil {
+			return err
+		}
+	case "CAA":
+		if req.Flags < 0 || req.Flags > 255 {
+			return fmt.Errorf("Flags must be between 0 and 255")
+		}
+		if req.Tag != "issue" && req.Tag != "issuewild" && req.Tag != "iodef" {
+			return fmt.Errorf("Tag must be one of issue, issuewild or iodef")
+		}
+	case "TXT":
+	case "SOA":
+		if id == 0 {
+			return fmt.Errorf("SOA records cannot be created")
+		}
+	default:
+		return fmt.Errorf("Type %s is not supported", req.Type)
+	}
+
+	// a CNAME cannot share its name with other records
+	for _, record := range s.domains[domain] {
+		if record.ID == id || !strings.EqualFold(record.Name, req.Name) {
+			continue
+		}
+		if record.Type == "CNAME" || req.Type == "CNAME" {
+			return fmt.Errorf("CNAME records cannot share a name with other records")
+		}
+	}
+
+	return nil
+}
+
+// hasHostname reports whether the data of records of type recType is a host
+// name, checked by validateHostname.
+func hasHostname(recType string) bool {
+	switch recType {
+	case "CNAME", "MX", "NS", "SRV":
+		return true
+	default:
+		return false
+	}
+}
+
+// validateHostname checks the data of a CNAME, MX, NS or SRV record, which
+// must be "@" or a fully qualified name with a trailing dot.
+func validateHostname(data string) error {
+	if data == "@" || (strings.HasSuffix(data, ".") && len(data) > 1) {
+		return nil
+	}
+	return fmt.Errorf("Data must be a fully qualified hostname ending with a dot, or @")
+}
+
+// paginate returns the range of the items on the requested page and the links
+// to the other pages. It writes a 400 response for invalid page parameters.
+func (s *Server) paginate(w http.ResponseWriter, r *http.Request, total int) (int, int, *godo.Links, bool) {
+	query := r.URL.Query()
+
+	page, perPage := 1, defaultPerPage
+	if v := query.Get("page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "bad_request", "page is invalid")
+			return 0, 0, nil, false
+		}
+		page = n
+	}
+	if v := query.Get("per_page"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "bad_request", "per_page is invalid")
+			return 0, 0, nil, false
+		}
+		perPage = min(n, maxPerPage)
+	}
+
+	lastPage := max((total+perPage-1)/perPage, 1)
+	start := min((page-1)*perPage, total)
+	end := min(start+perPage, total)
+
+	pageURL := func(n int) string {
+		q := url.Values{}
+		for k, v := range query {
+			q[k] = v
+		}
+		q.Set("page", strconv.Itoa(n))
+		q.Set("per_page", strconv.Itoa(perPage))
+		return s.URL + r.URL.Path + "?" + q.Encode()
+	}
+
+	links := &godo.Links{}
+	if page > 1 || page < lastPage {
+		links.Pages = &godo.Pages{}
+	}
+	if page > 1 {
+		links.Pages.First = pageURL(1)
+		links.Pages.Prev = pageURL(min(page-1, lastPage))
+	}
+	if page < lastPage {
+		links.Pages.Next = pageURL(page + 1)
+		links.Pages.Last = pageURL(lastPage)
+	}
+
+	return start, end, links, true
+}
+
+// fqdn returns the fully qualified name, without a trailing dot, of a record
+// name in domain.
+func fqdn(name, domain string) string {
+	if name == "@" {
+		return domain
+	}
+	return name + "." + domain
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, id, message string) {
+	writeJSON(w, status, map[string]string{
+		"id":         id,
+		"message":    message,
+		"request_id": w.Header().Get("x-request-id"),
+	})
+}
+
+// tokenTransport adds the API token to requests.
+type tokenTransport struct {
+	token string
+}
+
+func (t *tokenTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token := t.token
+	if token == "" {
+		token = "test-token"
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return http.DefaultTransport.RoundTrip(req)
+}
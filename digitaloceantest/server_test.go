@@ -0,0 +1,207 @@
+package digitaloceantest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestServer_records(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := s.Client()
+	ctx := context.Background()
+
+	if _, _, err := client.Domains.Create(ctx, &godo.DomainCreateRequest{Name: "example.com", IPAddress: "192.168.1.1"}); err != nil {
+		t.Fatalf("Domains.Create() error = %v", err)
+	}
+
+	// SOA, three NS and the A record for the IP address
+	records, _, err := client.Domains.Records(ctx, "example.com", nil)
+	if err != nil {
+		t.Fatalf("Domains.Records() error = %v", err)
+	}
+	if len(records) != 5 || records[0].Type != "SOA" || records[4].Data != "192.168.1.1" {
+		t.Errorf("Domains.Records() = %v, want SOA, NS and A records", records)
+	}
+
+	// The default NS records can be edited without restating their data
+	ns, _, err := client.Domains.EditRecord(ctx, "example.com", records[1].ID, &godo.DomainRecordEditRequest{TTL: 3600})
+	if err != nil {
+		t.Fatalf("Domains.EditRecord() of a default NS record error = %v", err)
+	}
+	if ns.Type != "NS" || ns.TTL != 3600 || ns.Data != "ns1.digitalocean.com." {
+		t.Errorf("Domains.EditRecord() of a default NS record = %+v, want its TTL changed", ns)
+	}
+
+	created, _, err := client.Domains.CreateRecord(ctx, "example.com", &godo.DomainRecordEditRequest{
+		Type: "MX", Name: "@", Data: "mail.example.com.", Priority: 10,
+	})
+	if err != nil {
+		t.Fatalf("Domains.CreateRecord() error = %v", err)
+	}
+	if created.ID == 0 || created.TTL != defaultTTL {
+		t.Errorf("Domains.CreateRecord() = %+v, want an ID and the default TTL", created)
+	}
+
+	edited, _, err := client.Domains.EditRecord(ctx, "example.com", created.ID, &godo.DomainRecordEditRequest{
+		Type: "MX", Name: "@", Data: "mx.example.com.", Priority: 20, TTL: 300,
+	})
+	if err != nil {
+		t.Fatalf("Domains.EditRecord() error = %v", err)
+	}
+	if edited.ID != created.ID || edited.Data != "mx.example.com." || edited.Priority != 20 {
+		t.Errorf("Domains.EditRecord() = %+v, want the edited record", edited)
+	}
+
+	byName, _, err := client.Domains.RecordsByTypeAndName(ctx, "example.com", "MX", "example.com", nil)
+	if err != nil || len(byName) != 1 || byName[0].ID != created.ID {
+		t.Errorf("Domains.RecordsByTypeAndName() = %v, %v, want the MX record", byName, err)
+	}
+
+	if _, err := client.Domains.DeleteRecord(ctx, "example.com", created.ID); err != nil {
+		t.Fatalf("Domains.DeleteRecord() error = %v", err)
+	}
+	if _, _, err := client.Domains.Record(ctx, "example.com", created.ID); !isStatus(err, http.StatusNotFound) {
+		t.Errorf("Domains.Record() error = %v, want 404", err)
+	}
+}
+
+func TestServer_validation(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := s.Client()
+	ctx := context.Background()
+
+	if _, err := s.AddDomain("example.com", godo.DomainRecord{Type: "A", Name: "www", Data: "192.168.1.1"}); err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		req  godo.DomainRecordEditRequest
+	}{
+		{name: "invalid IPv4", req: godo.DomainRecordEditRequest{Type: "A", Name: "a", Data: "::1"}},
+		{name: "TTL below minimum", req: godo.DomainRecordEditRequest{Type: "A", Name: "a", Data: "192.168.1.2", TTL: 10}},
+		{name: "relative target", req: godo.DomainRecordEditRequest{Type: "CNAME", Name: "c", Data: "target"}},
+		{name: "CNAME at apex", req: godo.DomainRecordEditRequest{Type: "CNAME", Name: "@", Data: "target.example.net."}},
+		{name: "CNAME conflict", req: godo.DomainRecordEditRequest{Type: "CNAME", Name: "www", Data: "target.example.net."}},
+		{name: "invalid CAA tag", req: godo.DomainRecordEditRequest{Type: "CAA", Name: "@", Data: "letsencrypt.org", Tag: "issues"}},
+		{name: "SRV without port", req: godo.DomainRecordEditRequest{Type: "SRV", Name: "_sip._tcp", Data: "sip.example.com.", Priority: 10}},
+		{name: "unsupported type", req: godo.DomainRecordEditRequest{Type: "SPF", Name: "@", Data: "v=spf1 -all"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := client.Domains.CreateRecord(ctx, "example.com", &tt.req); !isStatus(err, http.StatusUnprocessableEntity) {
+				t.Errorf("Domains.CreateRecord() error = %v, want 422", err)
+			}
+		})
+	}
+}
+
+func TestServer_pagination(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := s.Client()
+	ctx := context.Background()
+
+	var records []godo.DomainRecord
+	for i := 0; i < 45; i++ {
+		records = append(records, godo.DomainRecord{Type: "TXT", Name: fmt.Sprintf("txt%d", i), Data: "value"})
+	}
+	if _, err := s.AddDomain("example.com", records...); err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+
+	seen := 0
+	opt := &godo.ListOptions{Page: 1}
+	for {
+		page, resp, err := client.Domains.Records(ctx, "example.com", opt)
+		if err != nil {
+			t.Fatalf("Domains.Records() error = %v", err)
+		}
+		seen += len(page)
+		if resp.Links.IsLastPage() {
+			break
+		}
+		opt.Page++
+	}
+
+	if seen != 49 || opt.Page != 3 {
+		t.Errorf("Domains.Records() returned %d records on %d pages, want 49 on 3", seen, opt.Page)
+	}
+}
+
+func TestServer_Fail(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := s.Client()
+	ctx := context.Background()
+
+	s.Fail(http.StatusTooManyRequests, 1)
+	s.Fail(http.StatusServiceUnavailable, 1)
+
+	_, resp, err := client.Domains.List(ctx, nil)
+	if !isStatus(err, http.StatusTooManyRequests) || resp.Rate.Remaining != 0 {
+		t.Errorf("Domains.List() error = %v, rate = %+v, want 429 with exhausted rate limit", err, resp.Rate)
+	}
+	if _, _, err := client.Domains.List(ctx, nil); !isStatus(err, http.StatusServiceUnavailable) {
+		t.Errorf("Domains.List() error = %v, want 503", err)
+	}
+	if _, _, err := client.Domains.List(ctx, nil); err != nil {
+		t.Errorf("Domains.List() error = %v, want nil", err)
+	}
+	if got := s.Requests(); got != 3 {
+		t.Errorf("Server.Requests() = %d, want 3", got)
+	}
+}
+
+func TestServer_rateLimit(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	client := s.Client()
+	ctx := context.Background()
+
+	s.mu.Lock()
+	s.window = rateLimit
+	s.reset = time.Now().Add(-time.Second)
+	s.mu.Unlock()
+
+	_, resp, err := client.Domains.List(ctx, nil)
+	if err != nil {
+		t.Fatalf("Domains.List() error = %v", err)
+	}
+	if resp.Rate.Remaining != rateLimit-1 {
+		t.Errorf("Rate.Remaining = %d after the reset, want %d", resp.Rate.Remaining, rateLimit-1)
+	}
+	if got := s.Requests(); got != 1 {
+		t.Errorf("Server.Requests() = %d, want 1", got)
+	}
+}
+
+func TestServer_Token(t *testing.T) {
+	s := NewServer()
+	defer s.Close()
+	s.Token = "secret"
+
+	client := godo.NewFromToken("wrong")
+	client.BaseURL = s.Client().BaseURL
+	if _, _, err := client.Domains.List(context.Background(), nil); !isStatus(err, http.StatusUnauthorized) {
+		t.Errorf("Domains.List() error = %v, want 401", err)
+	}
+	if _, _, err := s.Client().Domains.List(context.Background(), nil); err != nil {
+		t.Errorf("Domains.List() error = %v, want nil", err)
+	}
+}
+
+// isStatus reports whether err is a godo error response with the given status
+func isStatus(err error, status int) bool {
+	var errResp *godo.ErrorResponse
+	return errors.As(err, &errResp) && errResp.Response.StatusCode == status
+}
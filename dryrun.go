@@ -0,0 +1,101 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+)
+
+// ChangeOp is the kind of change made to a record.
+type ChangeOp string
+
+const (
+	ChangeCreate ChangeOp = "create"
+	ChangeUpdate ChangeOp = "update"
+	ChangeDelete ChangeOp = "delete"
+)
+
+// Change is a single create, edit or delete of a record through the
+// DigitalOcean API.
+type Change struct {
+	Op   ChangeOp
+	Zone string
+	// ID is the DigitalOcean ID of the record to update or delete
+	ID int
+	// Record is the record as it is after the change, or the record that is
+	// deleted for ChangeDelete
+	Record libdns.Record
+	// Previous is the record before a ChangeUpdate, when it is known
+	Previous libdns.Record
+	// Request is the request sent for ChangeCreate and ChangeUpdate
+	Request *godo.DomainRecordEditRequest
+}
+
+func (c Change) String() string {
+	rr := c.Record.RR()
+	if c.Op == ChangeCreate {
+		return fmt.Sprintf("%s %s %s %q (ttl %s) in %s", c.Op, rr.Type, rr.Name, rr.Data, rr.TTL, c.Zone)
+	}
+	return fmt.Sprintf("%s %s %s %q (ttl %s, id %d) in %s", c.Op, rr.Type, rr.Name, rr.Data, rr.TTL, c.ID, c.Zone)
+}
+
+// ChangeSet is an ordered list of changes. It is safe for concurrent use.
+type ChangeSet struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+// Changes returns the changes in the set, in order.
+func (c *ChangeSet) Changes() []Change {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Change(nil), c.changes...)
+}
+
+func (c *ChangeSet) add(change Change) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.changes = append(c.changes, change)
+}
+
+type dryRunKey struct{}
+
+// WithDryRun returns a context under which AppendRecords, SetRecords and
+// DeleteRecords still read the current state of the zone, but add the creates,
+// edits and deletes they would issue to changes instead of sending them. The
+// records they return are the ones that would result; created records have no
+// ID yet.
+func WithDryRun(ctx context.Context, changes *ChangeSet) context.Context {
+	return context.WithValue(ctx, dryRunKey{}, changes)
+}
+
+// dryRun returns the change set of a dry run started with WithDryRun, or nil.
+func dryRun(ctx context.Context) *ChangeSet {
+	changes, _ := ctx.Value(dryRunKey{}).(*ChangeSet)
+	return changes
+}
+
+// recordFromRequest returns the typed record DigitalOcean would create from
+// entry, without an ID.
+func recordFromRequest(zone string, entry godo.DomainRecordEditRequest) libdns.Record {
+	rr := fromGodo(zone, godo.DomainRecord{
+		Type:     entry.Type,
+		Name:     entry.Name,
+		Data:     entry.Data,
+		Priority: entry.Priority,
+		Port:     entry.Port,
+		TTL:      entry.TTL,
+		Weight:   entry.Weight,
+		Flags:    entry.Flags,
+		Tag:      entry.Tag,
+	}).RR()
+
+	record, err := rr.Parse()
+	if err != nil {
+		return rr
+	}
+	return record
+}
@@ -0,0 +1,90 @@
+package digitalocean
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+)
+
+func TestDryRun_SetRecords(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 1, Type: "A", Name: "test", Data: "192.168.1.1", TTL: 3600},
+		{ID: 2, Type: "A", Name: "test", Data: "192.168.1.3", TTL: 3600},
+	}
+
+	p := setupTest(mockRecords, nil)
+	mock := p.client.Domains.(*mockDomainsService)
+
+	changes := &ChangeSet{}
+	ctx := WithDryRun(context.Background(), changes)
+
+	setRecords, err := p.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "A", Name: "test", Data: "192.168.1.2", TTL: 3600 * time.Second},
+		libdns.RR{Type: "MX", Name: "@", Data: "10 mail", TTL: 3600 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("Provider.SetRecords() error = %v", err)
+	}
+
+	// Nothing is sent to DigitalOcean
+	if len(mock.edited) != 0 || len(mock.deleted) != 0 {
+		t.Errorf("Provider.SetRecords() edited = %v, deleted = %v, want no API calls", mock.edited, mock.deleted)
+	}
+
+	// The returned records are the ones that would result
+	if len(setRecords) != 2 || recordID(setRecords[0]) != 1 || setRecords[0].RR().Data != "192.168.1.2" {
+		t.Errorf("Provider.SetRecords() = %v, want the planned records", setRecords)
+	}
+	if mx, ok := setRecords[1].(libdns.MX); !ok || mx.Target != "mail.example.com." || recordID(mx) != -1 {
+		t.Errorf("Provider.SetRecords()[1] = %#v, want MX record without ID", setRecords[1])
+	}
+
+	want := []struct {
+		op ChangeOp
+		id int
+	}{
+		{ChangeUpdate, 1},
+		{ChangeCreate, 0},
+		{ChangeDelete, 2},
+	}
+	got := changes.Changes()
+	if len(got) != len(want) {
+		t.Fatalf("ChangeSet has %d changes, want %d: %v", len(got), len(want), got)
+	}
+	for i, change := range got {
+		if change.Op != want[i].op || change.ID != want[i].id || change.Zone != "example.com" {
+			t.Errorf("ChangeSet[%d] = %v, want %s of ID %d", i, change, want[i].op, want[i].id)
+		}
+	}
+	if req := got[1].Request; req == nil || req.Priority != 10 || req.Data != "mail.example.com." {
+		t.Errorf("ChangeSet[1].Request = %+v, want MX request with priority 10", req)
+	}
+}
+
+func TestDryRun_DeleteRecords(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 1, Type: "TXT", Name: "_acme-challenge", Data: "token", TTL: 30},
+	}
+
+	p := setupTest(mockRecords, nil)
+	mock := p.client.Domains.(*mockDomainsService)
+
+	changes := &ChangeSet{}
+	ctx := WithDryRun(context.Background(), changes)
+
+	deletedRecords, err := p.DeleteRecords(ctx, "example.com.", []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge"},
+	})
+	if err != nil {
+		t.Fatalf("Provider.DeleteRecords() error = %v", err)
+	}
+	if len(deletedRecords) != 1 || len(mock.deleted) != 0 {
+		t.Errorf("Provider.DeleteRecords() = %v, deleted = %v, want a planned deletion only", deletedRecords, mock.deleted)
+	}
+	if got := changes.Changes(); len(got) != 1 || got[0].Op != ChangeDelete || got[0].ID != 1 {
+		t.Errorf("ChangeSet = %v, want deletion of ID 1", got)
+	}
+}
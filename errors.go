@@ -0,0 +1,63 @@
+package digitalocean
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+)
+
+// RecordError describes why a single record could not be processed.
+type RecordError struct {
+	// Record is the record the failed API call was made for
+	Record libdns.Record
+	Err    error
+}
+
+func (e RecordError) Error() string {
+	rr := e.Record.RR()
+	return fmt.Sprintf("%s record %q: %v", rr.Type, rr.Name, e.Err)
+}
+
+func (e RecordError) Unwrap() error {
+	return e.Err
+}
+
+// BatchError is returned by AppendRecords, SetRecords and DeleteRecords when
+// some, but not necessarily all, of the records could not be processed. The
+// records that were processed successfully are returned alongside the error
+// and are listed in Succeeded, carrying their DigitalOcean IDs, so callers can
+// resume or roll back.
+type BatchError struct {
+	Succeeded []libdns.Record
+	Failed    []RecordError
+}
+
+func (e *BatchError) Error() string {
+	total := len(e.Succeeded) + len(e.Failed)
+	if len(e.Failed) == 1 {
+		return fmt.Sprintf("1 of %d records failed: %v", total, e.Failed[0])
+	}
+	return fmt.Sprintf("%d of %d records failed, first error: %v", len(e.Failed), total, e.Failed[0])
+}
+
+// Unwrap returns the errors of the individual records, so errors.Is and
+// errors.As see through a BatchError.
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, len(e.Failed))
+	for i, failed := range e.Failed {
+		errs[i] = failed
+	}
+	return errs
+}
+
+// errorStatus returns the HTTP status of the DigitalOcean API error in err, or
+// 0 if err is not one.
+func errorStatus(err error) int {
+	var errResp *godo.ErrorResponse
+	if !errors.As(err, &errResp) || errResp.Response == nil {
+		return 0
+	}
+	return errResp.Response.StatusCode
+}
@@ -7,12 +7,12 @@ toolchain go1.24.3
 require (
 	github.com/digitalocean/godo v1.148.0
 	github.com/libdns/libdns v1.0.0
+	golang.org/x/oauth2 v0.23.0
 )
 
 require (
 	github.com/google/go-querystring v1.1.0 // indirect
 	github.com/hashicorp/go-cleanhttp v0.5.2 // indirect
 	github.com/hashicorp/go-retryablehttp v0.7.7 // indirect
-	golang.org/x/oauth2 v0.23.0 // indirect
 	golang.org/x/time v0.6.0 // indirect
 )
@@ -0,0 +1,66 @@
+package digitalocean
+
+import (
+	"context"
+	"iter"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+)
+
+// Records returns an iterator over the records in the zone, for callers that
+// scan a zone without needing all of its records at once. Records are read
+// from DigitalOcean one page of PageSize records at a time and yielded as
+// soon as their page arrives; no more pages are read once the loop stops.
+// The cache is not used.
+//
+// An error reading a page, or ctx being done before the next page is read, is
+// yielded once with a nil record and ends the iteration. Records are
+// de-duplicated by ID, as records added while the zone is read shift the
+// others to later pages.
+func (p *Provider) Records(ctx context.Context, zone string) iter.Seq2[libdns.Record, error] {
+	zone = p.unFQDN(zone)
+
+	return func(yield func(libdns.Record, error) bool) {
+		if err := p.getClient(); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		opt := &godo.ListOptions{Page: 1, PerPage: p.pageSize()}
+		seen := make(map[int]bool)
+		for {
+			if err := ctx.Err(); err != nil {
+				yield(nil, err)
+				return
+			}
+
+			var entries []godo.DomainRecord
+			var resp *godo.Response
+			err := p.do(ctx, &apiOp{method: "Domains.Records", zone: zone, page: opt.Page}, func() (*godo.Response, error) {
+				var err error
+				entries, resp, err = p.client.Domains.Records(ctx, zone, opt)
+				return resp, err
+			})
+			if err != nil {
+				yield(nil, err)
+				return
+			}
+
+			for _, entry := range entries {
+				if seen[entry.ID] {
+					continue
+				}
+				seen[entry.ID] = true
+				if !yield(fromGodo(zone, entry), nil) {
+					return
+				}
+			}
+
+			if isLastPage(resp) {
+				return
+			}
+			opt.Page++
+		}
+	}
+}
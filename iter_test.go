@@ -0,0 +1,91 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+	"github.com/wzzrd/libdns-digitalocean/digitaloceantest"
+)
+
+func TestIter_Records(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+
+	var records []godo.DomainRecord
+	for i := 0; i < 246; i++ {
+		records = append(records, godo.DomainRecord{Type: "A", Name: fmt.Sprintf("host%d", i), Data: "192.168.1.1", TTL: 3600})
+	}
+	if _, err := server.AddDomain("example.com", records...); err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+
+	// The zone holds 250 records with the SOA and NS records, three pages of 100
+	p := &Provider{APIToken: "test-token", BaseURL: server.URL, PageSize: 100}
+	ctx := context.Background()
+
+	count := 0
+	for _, err := range p.Records(ctx, "example.com.") {
+		if err != nil {
+			t.Fatalf("Provider.Records() error = %v", err)
+		}
+		count++
+	}
+	if count != 250 {
+		t.Errorf("Provider.Records() yielded %d records, want 250", count)
+	}
+	if server.Requests() != 3 {
+		t.Errorf("Provider.Records() made %d requests, want 3", server.Requests())
+	}
+
+	// No more pages are read once the loop stops
+	before := server.Requests()
+	var found libdns.Record
+	for record, err := range p.Records(ctx, "example.com.") {
+		if err != nil {
+			t.Fatalf("Provider.Records() error = %v", err)
+		}
+		if record.RR().Name == "host120" {
+			found = record
+			break
+		}
+	}
+	if found == nil {
+		t.Error("Provider.Records() did not yield host120")
+	}
+	if got := server.Requests() - before; got != 2 {
+		t.Errorf("Provider.Records() made %d requests to reach the second page, want 2", got)
+	}
+
+	// Cancellation is reported before the next page is read
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	before = server.Requests()
+	count = 0
+	var gotErr error
+	for _, err := range p.Records(ctx, "example.com.") {
+		if err != nil {
+			gotErr = err
+			continue
+		}
+		count++
+		cancel()
+	}
+	if !errors.Is(gotErr, context.Canceled) {
+		t.Errorf("Provider.Records() error = %v, want context.Canceled", gotErr)
+	}
+	if count != 100 || server.Requests()-before != 1 {
+		t.Errorf("Provider.Records() yielded %d records in %d requests after cancel, want 100 in 1", count, server.Requests()-before)
+	}
+
+	// Test error case
+	p = &Provider{}
+	for _, err := range p.Records(context.Background(), "example.com.") {
+		if err == nil {
+			t.Error("Provider.Records() without token expected error, got nil")
+		}
+	}
+}
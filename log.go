@@ -0,0 +1,157 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// redacted replaces secrets in log messages.
+const redacted = "[REDACTED]"
+
+// apiOp describes a godo call for p.do, which retries it and logs it.
+type apiOp struct {
+	// method is the godo method called, such as "Domains.CreateRecord"
+	method  string
+	zone    string
+	name    string
+	recType string
+	id      int
+	data    string
+	page    int
+	// create is set for calls that create something and are not idempotent,
+	// so they are only retried when DigitalOcean did not process them
+	create bool
+	// remove is set for calls that delete something, so a 404 Not Found
+	// after a retried 5xx means an earlier attempt already deleted it
+	remove bool
+}
+
+// logCall logs the outcome of a godo call made in the given number of
+// attempts since start: at debug level when it succeeded, at warn level when
+// DigitalOcean rejected it and at error level otherwise.
+func (p *Provider) logCall(ctx context.Context, op *apiOp, resp *godo.Response, err error, attempts int, start time.Time) {
+	if p.Logger == nil {
+		return
+	}
+
+	level, msg := slog.LevelDebug, "digitalocean API call"
+	if err != nil {
+		level, msg = slog.LevelError, "digitalocean API call failed"
+		if status := errorStatus(err); status >= 400 && status < 500 {
+			level = slog.LevelWarn
+		}
+	}
+
+	attrs := append(op.attrs(), responseAttrs(resp, err)...)
+	attrs = append(attrs, slog.Duration("duration", time.Since(start)), slog.Int("attempts", attempts))
+	if err != nil {
+		attrs = append(attrs, slog.String("error", redactTokens(err.Error())))
+	}
+	p.Logger.LogAttrs(ctx, level, msg, attrs...)
+}
+
+// logRetry logs a failed attempt of a godo call that is retried after wait.
+func (p *Provider) logRetry(ctx context.Context, op *apiOp, resp *godo.Response, err error, attempt int, wait time.Duration) {
+	if p.Logger == nil {
+		return
+	}
+
+	attrs := append(op.attrs(), responseAttrs(resp, err)...)
+	attrs = append(attrs,
+		slog.Int("attempt", attempt),
+		slog.Duration("wait", wait),
+		slog.String("error", redactTokens(err.Error())),
+	)
+	p.Logger.LogAttrs(ctx, slog.LevelWarn, "digitalocean API call retried", attrs...)
+}
+
+// attrs returns the log attributes describing the call, leaving out the ones
+// that are not set. TXT values that look like secrets are redacted.
+func (op *apiOp) attrs() []slog.Attr {
+	attrs := []slog.Attr{slog.String("op", op.method)}
+	if op.zone != "" {
+		attrs = append(attrs, slog.String("zone", op.zone))
+	}
+	if op.name != "" {
+		attrs = append(attrs, slog.String("name", op.name))
+	}
+	if op.recType != "" {
+		attrs = append(attrs, slog.String("type", op.recType))
+	}
+	if op.id != 0 {
+		attrs = append(attrs, slog.Int("id", op.id))
+	}
+	if op.data != "" {
+		data := op.data
+		if op.recType == "TXT" && looksSecret(op.name, data) {
+			data = redacted
+		}
+		attrs = append(attrs, slog.String("data", data))
+	}
+	if op.page != 0 {
+		attrs = append(attrs, slog.Int("page", op.page))
+	}
+	return attrs
+}
+
+// responseAttrs returns the HTTP status and DigitalOcean request ID of a
+// response, taken from the API error when there is one.
+func responseAttrs(resp *godo.Response, err error) []slog.Attr {
+	var status int
+	var requestID string
+	if resp != nil && resp.Response != nil {
+		status, requestID = resp.StatusCode, resp.Header.Get("x-request-id")
+	}
+	var errResp *godo.ErrorResponse
+	if errors.As(err, &errResp) {
+		status = errorStatus(err)
+		if errResp.RequestID != "" {
+			requestID = errResp.RequestID
+		}
+	}
+
+	var attrs []slog.Attr
+	if status != 0 {
+		attrs = append(attrs, slog.Int("status", status))
+	}
+	if requestID != "" {
+		attrs = append(attrs, slog.String("request_id", requestID))
+	}
+	return attrs
+}
+
+// secretValue matches values made of a single long run of characters used by
+// tokens and keys, such as ACME challenges and site verification codes.
+var secretValue = regexp.MustCompile(`^[A-Za-z0-9_\-+/.]{16,}=*$`)
+
+// looksSecret reports whether the TXT record with the given name and value
+// looks like it holds a secret: an ACME challenge, or a value that is, or
+// ends with after an "=", a long token without spaces. Policies and public
+// keys such as SPF and DKIM records contain spaces and are left as they are.
+func looksSecret(name, value string) bool {
+	if strings.HasPrefix(name, "_acme-challenge") {
+		return true
+	}
+	value = strings.Trim(value, `"`)
+	if strings.ContainsAny(value, " \t") {
+		return false
+	}
+	if i := strings.Index(value, "="); i >= 0 && i < len(value)-1 {
+		value = value[i+1:]
+	}
+	return secretValue.MatchString(value)
+}
+
+// apiToken matches DigitalOcean API tokens and bearer credentials.
+var apiToken = regexp.MustCompile(`(?i)\b(dop|doo|dor)_v1_[0-9a-f]+|\bbearer\s+\S+`)
+
+// redactTokens replaces API tokens in s, for example in error messages.
+func redactTokens(s string) string {
+	return apiToken.ReplaceAllString(s, redacted)
+}
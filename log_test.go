@@ -0,0 +1,106 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/wzzrd/libdns-digitalocean/digitaloceantest"
+)
+
+func TestLog_Logger(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+	if _, err := server.AddDomain("example.com"); err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+
+	var buf bytes.Buffer
+	p := &Provider{
+		APIToken:     "dop_v1_0123456789abcdef",
+		BaseURL:      server.URL,
+		Logger:       slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})),
+		RetryMinWait: time.Millisecond,
+		RetryMaxWait: time.Millisecond,
+	}
+	ctx := context.Background()
+
+	secret := "dGhpcyBpcyBhIHNlY3JldCBjaGFsbGVuZ2U"
+	server.Fail(http.StatusTooManyRequests, 1)
+	if _, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge", Text: secret, TTL: time.Minute},
+	}); err != nil {
+		t.Fatalf("Provider.AppendRecords() error = %v", err)
+	}
+	if _, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.CNAME{Name: "@", Target: "other.com.", TTL: time.Minute},
+	}); err == nil {
+		t.Fatal("Provider.AppendRecords() of a CNAME at the apex expected error, got nil")
+	}
+
+	if strings.Contains(buf.String(), secret) || strings.Contains(buf.String(), p.APIToken) {
+		t.Errorf("log holds secrets: %s", buf.String())
+	}
+
+	var entries []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]any
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("log line %q is not JSON: %v", line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("log holds %d messages, want 3: %s", len(entries), buf.String())
+	}
+
+	retried, created, failed := entries[0], entries[1], entries[2]
+	if retried["level"] != "WARN" || retried["msg"] != "digitalocean API call retried" || retried["status"] != 429.0 || retried["attempt"] != 1.0 {
+		t.Errorf("retry logged as %v", retried)
+	}
+	if created["level"] != "DEBUG" || created["op"] != "Domains.CreateRecord" || created["zone"] != "example.com" ||
+		created["name"] != "_acme-challenge" || created["type"] != "TXT" || created["data"] != redacted ||
+		created["status"] != 201.0 || created["attempts"] != 2.0 || created["id"] == nil ||
+		created["request_id"] == nil || created["duration"] == nil {
+		t.Errorf("created record logged as %v", created)
+	}
+	if failed["level"] != "WARN" || failed["msg"] != "digitalocean API call failed" || failed["status"] != 422.0 ||
+		failed["type"] != "CNAME" || failed["data"] != "other.com." || failed["request_id"] == nil || failed["error"] == nil {
+		t.Errorf("rejected record logged as %v", failed)
+	}
+}
+
+func TestLog_looksSecret(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		want  bool
+	}{
+		{"_acme-challenge", "short", true},
+		{"_acme-challenge.www", "dGhpcyBpcyBhIHNlY3JldA", true},
+		{"@", "google-site-verification=rXOxyZounnZasA8Z7oaD3c14JdjS9aKSWvsR1EbUSIQ", true},
+		{"@", "Xq1bCw9YHnS6kXo2LNd8Vr5Zp7Tg", true},
+		{"@", "v=spf1 include:_spf.google.com ~all", false},
+		{"mail._domainkey", "v=DKIM1; k=rsa; p=MIGfMA0GCSqGSIb3DQEBAQUAA4GNADCBiQKBgQC", false},
+		{"@", "hello", false},
+		{"@", "some text with spaces", false},
+	}
+	for _, tt := range tests {
+		if got := looksSecret(tt.name, tt.value); got != tt.want {
+			t.Errorf("looksSecret(%q, %q) = %v, want %v", tt.name, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestLog_redactTokens(t *testing.T) {
+	got := redactTokens("token dop_v1_0123456789abcdef rejected, header Authorization: Bearer abc.def")
+	if want := "token [REDACTED] rejected, header Authorization: [REDACTED]"; got != want {
+		t.Errorf("redactTokens() = %q, want %q", got, want)
+	}
+}
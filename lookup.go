@@ -0,0 +1,126 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// maxFilteredLookups is the number of RRsets up to which SetRecords and
+// DeleteRecords look them up one by one, rather than reading the whole zone.
+const maxFilteredLookups = 8
+
+// GetRecordsByName returns the records of the zone with the given name, of any
+// type. The name may be relative to the zone or fully qualified, with "@" for
+// the apex. Only the matching records are read from DigitalOcean, unless the
+// zone is cached.
+func (p *Provider) GetRecordsByName(ctx context.Context, zone, name string) ([]libdns.Record, error) {
+	if name == "" {
+		return nil, errors.New("record name is empty")
+	}
+	return p.getFilteredEntries(ctx, p.unFQDN(zone), name, "")
+}
+
+// GetRecordsByType returns the records of the zone of the given type. Only the
+// matching records are read from DigitalOcean, unless the zone is cached.
+func (p *Provider) GetRecordsByType(ctx context.Context, zone, recType string) ([]libdns.Record, error) {
+	if recType == "" {
+		return nil, errors.New("record type is empty")
+	}
+	return p.getFilteredEntries(ctx, p.unFQDN(zone), "", recType)
+}
+
+// GetRRSet returns the records of the zone with the given name and type. The
+// name may be relative to the zone or fully qualified, with "@" for the apex.
+// Only the matching records are read from DigitalOcean, unless the zone is
+// cached.
+func (p *Provider) GetRRSet(ctx context.Context, zone, name, recType string) ([]libdns.Record, error) {
+	if name == "" || recType == "" {
+		return nil, errors.New("record name or type is empty")
+	}
+	return p.getFilteredEntries(ctx, p.unFQDN(zone), name, recType)
+}
+
+// getFilteredEntries returns the records of the zone with the given name and
+// type, either of which may be empty to match any value. They are taken from
+// the cache if it is fresh.
+func (p *Provider) getFilteredEntries(ctx context.Context, zone, name, recType string) ([]libdns.Record, error) {
+	if name != "" {
+		name = relativeName(name, zone)
+		if name == "" {
+			name = "@"
+		}
+	}
+
+	entries, ok := p.cachedEntries(ctx, zone)
+	if !ok {
+		var err error
+		entries, err = p.listFilteredEntries(ctx, zone, name, recType)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var records []libdns.Record
+	for _, entry := range entries {
+		// the API filters too, but the cache does not
+		if name != "" && !strings.EqualFold(entry.Name, name) {
+			continue
+		}
+		if recType != "" && entry.Type != recType {
+			continue
+		}
+		records = append(records, fromGodo(zone, entry))
+	}
+
+	return records, nil
+}
+
+// lookupRRSets reads the records of the given RRsets from DigitalOcean,
+// bypassing the cache. An empty Type matches records of any type. The whole
+// zone is read instead if there are more than maxFilteredLookups RRsets, or if
+// one of them has no name.
+func (p *Provider) lookupRRSets(ctx context.Context, zone string, keys []RRSet) ([]libdns.Record, error) {
+	ctx = WithoutCache(ctx)
+
+	var lookups []RRSet
+	seen := make(map[RRSet]bool)
+	for _, key := range keys {
+		if key.Name == "" {
+			return p.getDNSEntries(ctx, zone)
+		}
+		if !seen[key] {
+			seen[key] = true
+			lookups = append(lookups, key)
+		}
+	}
+	if len(lookups) > maxFilteredLookups {
+		return p.getDNSEntries(ctx, zone)
+	}
+
+	results := make([][]libdns.Record, len(lookups))
+	errs := make([]error, len(lookups))
+	p.forEach(ctx, len(lookups), func(ctx context.Context, i int) {
+		results[i], errs[i] = p.getFilteredEntries(ctx, zone, lookups[i].Name, lookups[i].Type)
+	})
+
+	// a lookup by name only overlaps with lookups by name and type
+	var records []libdns.Record
+	found := make(map[int]bool)
+	for i, result := range results {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		for _, record := range result {
+			id, _ := idFromRecord(record)
+			if !found[id] {
+				found[id] = true
+				records = append(records, record)
+			}
+		}
+	}
+
+	return records, nil
+}
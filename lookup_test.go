@@ -0,0 +1,119 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+)
+
+func TestLookup_GetRRSet(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 1, Type: "A", Name: "www", Data: "192.168.1.1", TTL: 3600},
+		{ID: 2, Type: "AAAA", Name: "www", Data: "::1", TTL: 3600},
+		{ID: 3, Type: "A", Name: "@", Data: "192.168.1.2", TTL: 3600},
+	}
+
+	p := setupTest(mockRecords, nil)
+	mock := p.client.Domains.(*mockDomainsService)
+	ctx := context.Background()
+
+	records, err := p.GetRRSet(ctx, "example.com.", "www.example.com.", "A")
+	if err != nil || len(records) != 1 || recordID(records[0]) != 1 {
+		t.Errorf("Provider.GetRRSet() = %v, %v, want record 1", records, err)
+	}
+
+	records, err = p.GetRecordsByName(ctx, "example.com.", "www")
+	if err != nil || len(records) != 2 {
+		t.Errorf("Provider.GetRecordsByName() = %v, %v, want 2 records", records, err)
+	}
+
+	records, err = p.GetRecordsByType(ctx, "example.com.", "A")
+	if err != nil || len(records) != 2 {
+		t.Errorf("Provider.GetRecordsByType() = %v, %v, want 2 records", records, err)
+	}
+
+	records, err = p.GetRRSet(ctx, "example.com.", "@", "A")
+	if err != nil || len(records) != 1 || records[0].RR().Name != "@" {
+		t.Errorf("Provider.GetRRSet() = %v, %v, want the apex record", records, err)
+	}
+
+	want := "[A www.example.com  www.example.com A  A example.com]"
+	if got := fmt.Sprint(mock.lookups); got != want {
+		t.Errorf("lookups = %s, want %s", got, want)
+	}
+
+	// Test error cases
+	if _, err := p.GetRRSet(ctx, "example.com.", "", "A"); err == nil {
+		t.Error("Provider.GetRRSet() without name expected error, got nil")
+	}
+	p = setupTest(nil, errors.New("API error"))
+	if _, err := p.GetRecordsByName(ctx, "example.com.", "www"); err == nil {
+		t.Error("Provider.GetRecordsByName() expected error, got nil")
+	}
+}
+
+func TestLookup_lookupRRSets(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 1, Type: "A", Name: "www", Data: "192.168.1.1", TTL: 3600},
+		{ID: 2, Type: "AAAA", Name: "www", Data: "::1", TTL: 3600},
+		{ID: 3, Type: "TXT", Name: "other", Data: "text", TTL: 3600},
+	}
+
+	p := setupTest(mockRecords, nil)
+	mock := p.client.Domains.(*mockDomainsService)
+	ctx := context.Background()
+
+	// Overlapping lookups return each record once
+	records, err := p.lookupRRSets(ctx, "example.com", []RRSet{{Name: "www", Type: "A"}, {Name: "www"}, {Name: "www", Type: "A"}})
+	if err != nil {
+		t.Fatalf("Provider.lookupRRSets() error = %v", err)
+	}
+	ids := []int{}
+	for _, record := range records {
+		ids = append(ids, recordID(record))
+	}
+	sort.Ints(ids)
+	if fmt.Sprint(ids) != "[1 2]" || len(mock.lookups) != 2 {
+		t.Errorf("Provider.lookupRRSets() = %v in %d lookups, want [1 2] in 2", ids, len(mock.lookups))
+	}
+
+	// Many RRsets, or one without a name, read the whole zone
+	mock.lookups = nil
+	var keys []RRSet
+	for i := 0; i <= maxFilteredLookups; i++ {
+		keys = append(keys, RRSet{Name: fmt.Sprintf("name%d", i), Type: "A"})
+	}
+	for _, keys := range [][]RRSet{keys, {{Type: "A"}}} {
+		records, err = p.lookupRRSets(ctx, "example.com", keys)
+		if err != nil || len(records) != 3 || len(mock.lookups) != 0 {
+			t.Errorf("Provider.lookupRRSets() = %v, %v after %d lookups, want the whole zone", records, err, len(mock.lookups))
+		}
+	}
+}
+
+func TestLookup_SetRecords(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 1, Type: "TXT", Name: "_acme-challenge", Data: "old", TTL: 30},
+		{ID: 2, Type: "A", Name: "www", Data: "192.168.1.1", TTL: 3600},
+	}
+
+	p := setupTest(mockRecords, nil)
+	mock := p.client.Domains.(*mockDomainsService)
+	ctx := context.Background()
+
+	// Only the RRset that is set is read
+	if _, err := p.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.TXT{Name: "_acme-challenge", Text: "new", TTL: 30 * time.Second},
+	}); err != nil {
+		t.Fatalf("Provider.SetRecords() error = %v", err)
+	}
+	if fmt.Sprint(mock.lookups) != "[TXT _acme-challenge.example.com]" || fmt.Sprint(mock.edited) != "[1]" {
+		t.Errorf("Provider.SetRecords() lookups = %v, edited = %v, want one TXT lookup and record 1 edited", mock.lookups, mock.edited)
+	}
+}
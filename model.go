@@ -1,6 +1,8 @@
 package digitalocean
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 	"time"
 
@@ -8,7 +10,8 @@ import (
 	"github.com/libdns/libdns"
 )
 
-// DNS custom struct that implements the libdns.Record interface and keeps the ID field used internally
+// DNS custom struct that implements the libdns.Record interface and keeps the ID field used internally.
+// It is only returned for record types that have no dedicated libdns type, such as SOA.
 type DNS struct {
 	Record libdns.RR
 	ID     string
@@ -18,44 +21,161 @@ func (d DNS) RR() libdns.RR {
 	return d.Record
 }
 
-// fromRecord creates a dns struct from a libdns.Record, with an optional ID
-func fromRecord(record libdns.Record, id string) DNS {
+// ProviderData is stored in the ProviderData field of the typed libdns records
+// returned by this package, and carries the DigitalOcean ID of the record.
+type ProviderData struct {
+	ID int
+}
+
+// RecordID returns the DigitalOcean ID of a record returned by this package, and
+// whether the record carried one at all.
+func RecordID(record libdns.Record) (int, bool) {
+	id, err := idFromRecord(record)
+	return id, err == nil
+}
+
+// withID returns the record as its typed libdns equivalent, with the DigitalOcean
+// ID stored in ProviderData. Records without a dedicated libdns type, or whose
+// data cannot be parsed, are wrapped in a DNS struct instead.
+func withID(record libdns.Record, id int) libdns.Record {
 	rr := record.RR()
-	return DNS{
-		Record: rr,
-		ID:     id,
+	data := ProviderData{ID: id}
+
+	parsed, err := rr.Parse()
+	if err != nil {
+		return DNS{Record: rr, ID: strconv.Itoa(id)}
 	}
+
+	switch rec := parsed.(type) {
+	case libdns.Address:
+		rec.ProviderData = data
+		return rec
+	case libdns.CAA:
+		rec.ProviderData = data
+		return rec
+	case libdns.CNAME:
+		rec.ProviderData = data
+		return rec
+	case libdns.MX:
+		rec.ProviderData = data
+		return rec
+	case libdns.NS:
+		rec.ProviderData = data
+		return rec
+	case libdns.SRV:
+		rec.ProviderData = data
+		return rec
+	case libdns.ServiceBinding:
+		rec.ProviderData = data
+		return rec
+	case libdns.TXT:
+		rec.ProviderData = data
+		return rec
+	default:
+		return DNS{Record: rr, ID: strconv.Itoa(id)}
+	}
+}
+
+// fromGodo creates a typed libdns record from godo.DomainRecord. Names are made
+// relative to the zone and targets fully qualified.
+func fromGodo(zone string, entry godo.DomainRecord) libdns.Record {
+	if hasTarget(entry.Type) {
+		entry.Data = absoluteTarget(entry.Data, zone)
+	}
+
+	return withID(libdns.RR{
+		Name: relativeName(entry.Name, zone),
+		Data: dataFromGodo(entry),
+		Type: entry.Type,
+		TTL:  time.Duration(entry.TTL) * time.Second,
+	}, entry.ID)
 }
 
-// fromGodo creates a dns struct from godo.DomainRecord
-func fromGodo(entry godo.DomainRecord) DNS {
-	return DNS{
-		Record: libdns.RR{
-			Name: entry.Name,
-			Data: entry.Data,
-			Type: entry.Type,
-			TTL:  time.Duration(entry.TTL) * time.Second,
-		},
-		ID: strconv.Itoa(entry.ID),
+// dataFromGodo builds the libdns data string for a godo.DomainRecord. DigitalOcean
+// keeps the MX priority, the SRV priority/weight/port and the CAA flags/tag in
+// separate fields, which are folded back into the form libdns.RR.Parse expects.
+func dataFromGodo(entry godo.DomainRecord) string {
+	switch entry.Type {
+	case "MX":
+		return fmt.Sprintf("%d %s", entry.Priority, entry.Data)
+	case "SRV":
+		return fmt.Sprintf("%d %d %d %s", entry.Priority, entry.Weight, entry.Port, entry.Data)
+	case "CAA":
+		return fmt.Sprintf("%d %s %q", entry.Flags, entry.Tag, entry.Data)
+	default:
+		return entry.Data
 	}
 }
 
-// recordToGoDo converts a libdns.RR to the DigitalOcean API format
-func recordToGoDo(record libdns.Record) godo.DomainRecordEditRequest {
+// recordToGoDo converts a libdns.RR to the DigitalOcean API format. For MX, SRV
+// and CAA records the data is parsed and split into the extra fields the API uses.
+// Names are made relative to the zone, with "@" for the apex, and targets are
+// written the way DigitalOcean expects them.
+func recordToGoDo(zone string, record libdns.Record) (godo.DomainRecordEditRequest, error) {
 	rr := record.RR()
-	return godo.DomainRecordEditRequest{
-		Name: rr.Name,
+	name, err := zoneName(rr.Name, zone)
+	if err != nil {
+		return godo.DomainRecordEditRequest{}, err
+	}
+	entry := godo.DomainRecordEditRequest{
+		Name: name,
 		Data: rr.Data,
 		Type: rr.Type,
 		TTL:  int(rr.TTL.Seconds()),
 	}
+
+	switch rr.Type {
+	case "MX", "SRV", "CAA":
+		parsed, err := rr.Parse()
+		if err != nil {
+			return entry, err
+		}
+
+		switch rec := parsed.(type) {
+		case libdns.MX:
+			entry.Priority = int(rec.Preference)
+			entry.Data = rec.Target
+		case libdns.SRV:
+			entry.Priority = int(rec.Priority)
+			entry.Weight = int(rec.Weight)
+			entry.Port = int(rec.Port)
+			entry.Data = rec.Target
+		case libdns.CAA:
+			entry.Flags = int(rec.Flags)
+			entry.Tag = rec.Tag
+			entry.Data = rec.Value
+		}
+	}
+
+	if hasTarget(entry.Type) {
+		entry.Data = toDOTarget(entry.Data, zone)
+	}
+
+	return entry, nil
 }
 
 // idFromRecord get the ID from a libdns.Record
 func idFromRecord(record libdns.Record) (int, error) {
 	var raw string
-	if dns, ok := record.(DNS); ok {
-		raw = dns.ID
+	switch rec := record.(type) {
+	case DNS:
+		raw = rec.ID
+	case libdns.Address:
+		return idFromProviderData(rec.ProviderData)
+	case libdns.CAA:
+		return idFromProviderData(rec.ProviderData)
+	case libdns.CNAME:
+		return idFromProviderData(rec.ProviderData)
+	case libdns.MX:
+		return idFromProviderData(rec.ProviderData)
+	case libdns.NS:
+		return idFromProviderData(rec.ProviderData)
+	case libdns.SRV:
+		return idFromProviderData(rec.ProviderData)
+	case libdns.ServiceBinding:
+		return idFromProviderData(rec.ProviderData)
+	case libdns.TXT:
+		return idFromProviderData(rec.ProviderData)
 	}
 	id, err := strconv.Atoi(raw)
 	if err != nil {
@@ -63,3 +183,12 @@ func idFromRecord(record libdns.Record) (int, error) {
 	}
 	return id, nil
 }
+
+// idFromProviderData gets the ID from the ProviderData field of a typed libdns record
+func idFromProviderData(data any) (int, error) {
+	pd, ok := data.(ProviderData)
+	if !ok {
+		return 0, errors.New("record has no DigitalOcean ID")
+	}
+	return pd.ID, nil
+}
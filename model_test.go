@@ -0,0 +1,127 @@
+package digitalocean
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+)
+
+func TestModel_roundTrip(t *testing.T) {
+	tests := []struct {
+		name   string
+		record libdns.Record
+		want   godo.DomainRecordEditRequest
+	}{
+		{
+			name:   "A",
+			record: libdns.RR{Type: "A", Name: "www", Data: "192.168.1.1", TTL: 3600 * time.Second},
+			want:   godo.DomainRecordEditRequest{Type: "A", Name: "www", Data: "192.168.1.1", TTL: 3600},
+		},
+		{
+			name:   "MX",
+			record: libdns.MX{Name: "@", Preference: 10, Target: "mail.example.com.", TTL: 3600 * time.Second},
+			want:   godo.DomainRecordEditRequest{Type: "MX", Name: "@", Data: "mail.example.com.", Priority: 10, TTL: 3600},
+		},
+		{
+			name: "SRV",
+			record: libdns.SRV{Service: "sip", Transport: "tcp", Name: "@", Priority: 10, Weight: 20, Port: 5060,
+				Target: "sip.example.com.", TTL: 1800 * time.Second},
+			want: godo.DomainRecordEditRequest{Type: "SRV", Name: "_sip._tcp", Data: "sip.example.com.", Priority: 10,
+				Weight: 20, Port: 5060, TTL: 1800},
+		},
+		{
+			name:   "CAA",
+			record: libdns.CAA{Name: "@", Flags: 128, Tag: "issue", Value: "letsencrypt.org", TTL: 3600 * time.Second},
+			want:   godo.DomainRecordEditRequest{Type: "CAA", Name: "@", Data: "letsencrypt.org", Flags: 128, Tag: "issue", TTL: 3600},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := recordToGoDo("example.com", tt.record)
+			if err != nil {
+				t.Fatalf("recordToGoDo() error = %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("recordToGoDo() = %+v, want %+v", got, tt.want)
+			}
+
+			// Convert back and verify nothing was lost on the way
+			back := fromGodo("example.com", godo.DomainRecord{
+				ID:       1,
+				Type:     got.Type,
+				Name:     got.Name,
+				Data:     got.Data,
+				Priority: got.Priority,
+				Port:     got.Port,
+				TTL:      got.TTL,
+				Weight:   got.Weight,
+				Flags:    got.Flags,
+				Tag:      got.Tag,
+			})
+			if back.RR() != tt.record.RR() {
+				t.Errorf("fromGodo() = %+v, want %+v", back.RR(), tt.record.RR())
+			}
+		})
+	}
+}
+
+func TestModel_recordToGoDoInvalid(t *testing.T) {
+	_, err := recordToGoDo("example.com", libdns.RR{Type: "MX", Name: "@", Data: "mail.example.com."})
+	if err == nil {
+		t.Error("recordToGoDo() expected error for MX record without preference, got nil")
+	}
+
+	_, err = recordToGoDo("example.com", libdns.RR{Type: "A", Name: "foo.other.org.", Data: "192.168.1.1"})
+	if !errors.Is(err, ErrOutsideZone) {
+		t.Errorf("recordToGoDo() error = %v for a name outside the zone, want ErrOutsideZone", err)
+	}
+}
+
+func TestModel_fromGodoTyped(t *testing.T) {
+	tests := []struct {
+		name  string
+		entry godo.DomainRecord
+		check func(libdns.Record) bool
+	}{
+		{
+			name:  "AAAA",
+			entry: godo.DomainRecord{ID: 1, Type: "AAAA", Name: "www", Data: "2001:db8::1", TTL: 3600},
+			check: func(r libdns.Record) bool { rec, ok := r.(libdns.Address); return ok && rec.IP.Is6() },
+		},
+		{
+			name:  "TXT",
+			entry: godo.DomainRecord{ID: 2, Type: "TXT", Name: "www", Data: "hello world", TTL: 3600},
+			check: func(r libdns.Record) bool { rec, ok := r.(libdns.TXT); return ok && rec.Text == "hello world" },
+		},
+		{
+			name:  "MX",
+			entry: godo.DomainRecord{ID: 3, Type: "MX", Name: "@", Data: "mail.example.com.", Priority: 10, TTL: 3600},
+			check: func(r libdns.Record) bool { rec, ok := r.(libdns.MX); return ok && rec.Preference == 10 },
+		},
+		{
+			name:  "SOA",
+			entry: godo.DomainRecord{ID: 4, Type: "SOA", Name: "@", Data: "1800", TTL: 1800},
+			check: func(r libdns.Record) bool { _, ok := r.(DNS); return ok },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := fromGodo("example.com", tt.entry)
+			if !tt.check(got) {
+				t.Errorf("fromGodo() = %#v, unexpected type or content", got)
+			}
+			if id, ok := RecordID(got); !ok || id != tt.entry.ID {
+				t.Errorf("RecordID() = %v, %v, want %v, true", id, ok, tt.entry.ID)
+			}
+		})
+	}
+
+	if _, ok := RecordID(libdns.TXT{Name: "www", Text: "hello world"}); ok {
+		t.Error("RecordID() reported an ID for a record without one")
+	}
+}
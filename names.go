@@ -0,0 +1,148 @@
+package digitalocean
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// ErrOutsideZone is returned for records whose name is a fully qualified name
+// outside the zone they are meant for.
+var ErrOutsideZone = errors.New("record name is outside the zone")
+
+// relativeName converts a record name to the form used by both libdns and
+// DigitalOcean: relative to the zone, with "@" for the apex. Callers often pass
+// absolute names, so names inside the zone are made relative whether or not
+// they carry a trailing dot. Empty names and names outside the zone are
+// returned as-is.
+func relativeName(name, zone string) string {
+	if name == "" || name == "@" {
+		return name
+	}
+
+	zone = strings.ToLower(strings.TrimSuffix(zone, "."))
+	trimmed := strings.TrimSuffix(name, ".")
+	lower := strings.ToLower(trimmed)
+
+	switch {
+	case lower == zone:
+		return "@"
+	case strings.HasSuffix(lower, "."+zone):
+		return trimmed[:len(trimmed)-len(zone)-1]
+	default:
+		return name
+	}
+}
+
+// zoneName is relativeName for the names of records written to the zone: an
+// empty name stands for the apex, and names outside the zone are rejected
+// rather than sent to DigitalOcean, which would take them as relative names.
+func zoneName(name, zone string) (string, error) {
+	name = relativeName(name, zone)
+	switch {
+	case name == "":
+		return "@", nil
+	case strings.HasSuffix(name, "."):
+		return "", fmt.Errorf("%s is not in zone %s: %w", name, strings.TrimSuffix(zone, "."), ErrOutsideZone)
+	default:
+		return name, nil
+	}
+}
+
+// absoluteTarget converts the target host of a CNAME, MX, NS or SRV record to a
+// fully qualified name with a trailing dot. "@" refers to the zone apex and a
+// single label is taken to be relative to the zone, while other names without a
+// trailing dot are assumed to be fully qualified already.
+func absoluteTarget(target, zone string) string {
+	zone = strings.TrimSuffix(zone, ".")
+
+	switch {
+	case target == "" || strings.HasSuffix(target, "."):
+		return target
+	case target == "@":
+		return zone + "."
+	case !strings.Contains(target, "."):
+		return target + "." + zone + "."
+	default:
+		return target + "."
+	}
+}
+
+// toDOTarget converts the target host of a CNAME, MX, NS or SRV record to the
+// form DigitalOcean expects: "@" for the zone apex, a fully qualified name with a
+// trailing dot otherwise.
+func toDOTarget(target, zone string) string {
+	target = absoluteTarget(target, zone)
+	if strings.EqualFold(target, strings.TrimSuffix(zone, ".")+".") {
+		return "@"
+	}
+	return target
+}
+
+// hasTarget reports whether the data of records of type recType ends with a
+// host name.
+func hasTarget(recType string) bool {
+	switch recType {
+	case "CNAME", "MX", "NS", "SRV":
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeRR returns rr with its name relative to the zone, "@" for the apex
+// including an empty name, and, for record types that point at another host,
+// an absolute target in its data. This is the form records read from
+// DigitalOcean are in, so normalized input records can be compared with them
+// directly. It fails for names outside the zone.
+func normalizeRR(zone string, rr libdns.RR) (libdns.RR, error) {
+	name, err := zoneName(rr.Name, zone)
+	if err != nil {
+		return rr, err
+	}
+	rr.Name = name
+
+	// data made of whitespace only has no target to rewrite
+	if fields := strings.Fields(rr.Data); hasTarget(rr.Type) && len(fields) > 0 {
+		last := len(fields) - 1
+		fields[last] = absoluteTarget(fields[last], zone)
+		rr.Data = strings.Join(fields, " ")
+	}
+
+	return rr, nil
+}
+
+// normalizeRecord is like normalizeRR, but keeps the DigitalOcean ID of the
+// record if it has one. A record with an ID and no name keeps its empty name,
+// as DeleteRecords matches it by ID alone.
+func normalizeRecord(zone string, record libdns.Record) (libdns.Record, error) {
+	rr, err := normalizeRR(zone, record.RR())
+	if err != nil {
+		return record, err
+	}
+	if id, err := idFromRecord(record); err == nil {
+		if record.RR().Name == "" {
+			rr.Name = ""
+		}
+		return withID(rr, id), nil
+	}
+	return rr, nil
+}
+
+// recordFQDN returns the fully qualified name of a record, without a trailing
+// dot, which is how DigitalOcean expects names when filtering records.
+func recordFQDN(name, zone string) string {
+	zone = strings.TrimSuffix(zone, ".")
+	name = relativeName(name, zone)
+
+	switch {
+	case name == "" || name == "@":
+		return zone
+	case strings.HasSuffix(name, "."):
+		return strings.TrimSuffix(name, ".")
+	default:
+		return name + "." + zone
+	}
+}
@@ -0,0 +1,152 @@
+package digitalocean
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/libdns/libdns"
+)
+
+func TestNames_relativeName(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		zone   string
+		want   string
+	}{
+		{name: "apex", record: "@", zone: "example.com", want: "@"},
+		{name: "empty", record: "", zone: "example.com", want: ""},
+		{name: "relative", record: "www", zone: "example.com", want: "www"},
+		{name: "nested relative", record: "a.b", zone: "example.com", want: "a.b"},
+		{name: "zone with trailing dot", record: "example.com.", zone: "example.com", want: "@"},
+		{name: "zone without trailing dot", record: "example.com", zone: "example.com.", want: "@"},
+		{name: "absolute", record: "www.example.com.", zone: "example.com", want: "www"},
+		{name: "absolute without trailing dot", record: "www.example.com", zone: "example.com.", want: "www"},
+		{name: "absolute mixed case", record: "WWW.Example.COM.", zone: "example.com", want: "WWW"},
+		{name: "different zone", record: "www.example.net.", zone: "example.com", want: "www.example.net."},
+		{name: "zone suffix without label boundary", record: "wwwexample.com", zone: "example.com", want: "wwwexample.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := relativeName(tt.record, tt.zone); got != tt.want {
+				t.Errorf("relativeName(%q, %q) = %q, want %q", tt.record, tt.zone, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNames_targets(t *testing.T) {
+	tests := []struct {
+		name     string
+		target   string
+		zone     string
+		absolute string
+		do       string
+	}{
+		{name: "apex", target: "@", zone: "example.com", absolute: "example.com.", do: "@"},
+		{name: "zone", target: "example.com", zone: "example.com.", absolute: "example.com.", do: "@"},
+		{name: "zone with trailing dot", target: "example.com.", zone: "example.com", absolute: "example.com.", do: "@"},
+		{name: "single label", target: "mail", zone: "example.com", absolute: "mail.example.com.", do: "mail.example.com."},
+		{name: "absolute", target: "mail.example.net.", zone: "example.com", absolute: "mail.example.net.", do: "mail.example.net."},
+		{name: "absolute without trailing dot", target: "mail.example.net", zone: "example.com", absolute: "mail.example.net.", do: "mail.example.net."},
+		{name: "root", target: ".", zone: "example.com", absolute: ".", do: "."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := absoluteTarget(tt.target, tt.zone); got != tt.absolute {
+				t.Errorf("absoluteTarget(%q, %q) = %q, want %q", tt.target, tt.zone, got, tt.absolute)
+			}
+			if got := toDOTarget(tt.target, tt.zone); got != tt.do {
+				t.Errorf("toDOTarget(%q, %q) = %q, want %q", tt.target, tt.zone, got, tt.do)
+			}
+		})
+	}
+}
+
+func TestNames_normalizeRR(t *testing.T) {
+	tests := []struct {
+		name    string
+		rr      libdns.RR
+		want    libdns.RR
+		wantErr bool
+	}{
+		{
+			name: "A",
+			rr:   libdns.RR{Type: "A", Name: "www.example.com.", Data: "192.168.1.1"},
+			want: libdns.RR{Type: "A", Name: "www", Data: "192.168.1.1"},
+		},
+		{
+			name: "CNAME",
+			rr:   libdns.RR{Type: "CNAME", Name: "www", Data: "@"},
+			want: libdns.RR{Type: "CNAME", Name: "www", Data: "example.com."},
+		},
+		{
+			name: "MX",
+			rr:   libdns.RR{Type: "MX", Name: "example.com", Data: "10 mail"},
+			want: libdns.RR{Type: "MX", Name: "@", Data: "10 mail.example.com."},
+		},
+		{
+			name: "SRV",
+			rr:   libdns.RR{Type: "SRV", Name: "_sip._tcp.example.com.", Data: "10 20 5060 sip.example.net"},
+			want: libdns.RR{Type: "SRV", Name: "_sip._tcp", Data: "10 20 5060 sip.example.net."},
+		},
+		{
+			name: "empty name is the apex",
+			rr:   libdns.RR{Type: "A", Name: "", Data: "192.168.1.1"},
+			want: libdns.RR{Type: "A", Name: "@", Data: "192.168.1.1"},
+		},
+		{
+			name: "CNAME without target",
+			rr:   libdns.RR{Type: "CNAME", Name: "x", Data: " "},
+			want: libdns.RR{Type: "CNAME", Name: "x", Data: " "},
+		},
+		{
+			name:    "outside the zone",
+			rr:      libdns.RR{Type: "A", Name: "foo.other.org.", Data: "192.168.1.1"},
+			wantErr: true,
+		},
+		{
+			name: "TXT is left alone",
+			rr:   libdns.RR{Type: "TXT", Name: "@", Data: "mail"},
+			want: libdns.RR{Type: "TXT", Name: "@", Data: "mail"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeRR("example.com", tt.rr)
+			if tt.wantErr {
+				if !errors.Is(err, ErrOutsideZone) {
+					t.Errorf("normalizeRR() error = %v, want ErrOutsideZone", err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("normalizeRR() = %+v, %v, want %+v", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNames_recordFQDN(t *testing.T) {
+	tests := []struct {
+		name   string
+		record string
+		want   string
+	}{
+		{name: "apex", record: "@", want: "example.com"},
+		{name: "relative", record: "www", want: "www.example.com"},
+		{name: "absolute", record: "www.example.com.", want: "www.example.com"},
+		{name: "different zone", record: "www.example.net.", want: "www.example.net"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := recordFQDN(tt.record, "example.com."); got != tt.want {
+				t.Errorf("recordFQDN(%q) = %q, want %q", tt.record, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package digitalocean
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+const (
+	// minTTL is the lowest TTL DigitalOcean accepts
+	minTTL = 30 * time.Second
+	// defaultTTL is the TTL DigitalOcean gives records created without one
+	defaultTTL = 1800 * time.Second
+)
+
+// Plan is the ordered list of changes that turns the records of a zone into a
+// desired state. Deletions come first, then edits, then creations, so that for
+// example a CNAME can replace other records of the same name. Within each of
+// these steps, changes are ordered by RRset.
+type Plan struct {
+	// Zone is the zone the plan applies to, without a trailing dot
+	Zone    string
+	Changes []Change
+}
+
+// ByRRSet returns the changes of the plan grouped by the RRset they belong to.
+func (pl *Plan) ByRRSet() map[RRSet][]Change {
+	byRRSet := make(map[RRSet][]Change)
+	for _, change := range pl.Changes {
+		rr := change.Record.RR()
+		key := RRSet{Name: rr.Name, Type: rr.Type}
+		byRRSet[key] = append(byRRSet[key], change)
+	}
+	return byRRSet
+}
+
+// Plan compares the records in the zone with desired, the complete set of
+// records the zone should hold, and returns the changes that make the zone
+// match it. Nothing is changed until the plan is passed to Apply.
+//
+// Records DigitalOcean manages itself, the SOA record and the NS records at the
+// apex, are left out of the comparison on both sides. Names of desired records
+// may be relative or fully qualified, with "@" or an empty name for the apex.
+// TTLs below DigitalOcean's minimum of 30 seconds are raised to it, and a zero
+// TTL stands for DigitalOcean's default of 1800 seconds.
+func (p *Provider) Plan(ctx context.Context, zone string, desired []libdns.Record) (*Plan, error) {
+	zone = p.unFQDN(zone)
+
+	existing, err := p.getDNSEntries(WithoutCache(ctx), zone)
+	if err != nil {
+		return nil, err
+	}
+
+	var current []libdns.Record
+	for _, record := range existing {
+		if !managedByDigitalOcean(record) {
+			current = append(current, record)
+		}
+	}
+
+	var wanted []libdns.Record
+	for _, record := range desired {
+		rr, err := normalizeRR(zone, record.RR())
+		if err != nil {
+			return nil, RecordError{Record: record, Err: err}
+		}
+		rr.TTL = clampTTL(rr.TTL)
+		if !managedByDigitalOcean(rr) {
+			wanted = append(wanted, rr)
+		}
+	}
+
+	keys := rrsetKeys(append(append([]libdns.Record(nil), current...), wanted...))
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Name != keys[j].Name {
+			return keys[i].Name < keys[j].Name
+		}
+		return keys[i].Type < keys[j].Type
+	})
+
+	snapshot := snapshotOf(current)
+	var deletes, updates, creates []Change
+	for _, key := range keys {
+		diff := diffRRSet(key.filter(current), key.filter(wanted))
+
+		for _, record := range diff.remove {
+			id, _ := idFromRecord(record)
+			deletes = append(deletes, Change{Op: ChangeDelete, Zone: zone, ID: id, Record: record})
+		}
+		for _, record := range diff.update {
+			entry, err := recordToGoDo(zone, record)
+			if err != nil {
+				return nil, RecordError{Record: record, Err: err}
+			}
+			id, _ := idFromRecord(record)
+			updates = append(updates, Change{
+				Op:       ChangeUpdate,
+				Zone:     zone,
+				ID:       id,
+				Record:   withID(recordFromRequest(zone, entry), id),
+				Previous: snapshot[id],
+				Request:  &entry,
+			})
+		}
+		for _, record := range diff.create {
+			entry, err := recordToGoDo(zone, record)
+			if err != nil {
+				return nil, RecordError{Record: record, Err: err}
+			}
+			creates = append(creates, Change{Op: ChangeCreate, Zone: zone, Record: recordFromRequest(zone, entry), Request: &entry})
+		}
+	}
+
+	return &Plan{Zone: zone, Changes: append(append(deletes, updates...), creates...)}, nil
+}
+
+// Apply makes the changes of a plan returned by Plan, in order. Changes of the
+// same kind are made in parallel, bounded by MaxConcurrency. If some of them
+// fail, Apply stops before the next kind of change and returns a *BatchError,
+// or rolls back and returns a *RollbackError in transactional mode. It returns
+// the records that were edited or created.
+//
+// The plan is not checked against the zone again: records that were changed or
+// deleted since it was made cause their edit or deletion to fail.
+func (p *Provider) Apply(ctx context.Context, plan *Plan) ([]libdns.Record, error) {
+	unlock := p.lockZone(plan.Zone)
+	defer unlock()
+
+	var toDelete, toUpdate, toCreate, previous []libdns.Record
+	for _, change := range plan.Changes {
+		switch change.Op {
+		case ChangeDelete:
+			toDelete = append(toDelete, change.Record)
+		case ChangeUpdate:
+			toUpdate = append(toUpdate, change.Record)
+			if change.Previous != nil {
+				previous = append(previous, change.Previous)
+			}
+		case ChangeCreate:
+			toCreate = append(toCreate, change.Record)
+		}
+	}
+
+	j := journal{snapshot: snapshotOf(previous)}
+	var succeeded []libdns.Record
+	var failed []RecordError
+
+	j.deleted, failed = p.applyEach(ctx, plan.Zone, toDelete, p.removeDNSEntry)
+	if len(failed) == 0 {
+		j.updated, failed = p.applyEach(ctx, plan.Zone, toUpdate, p.updateDNSEntry)
+		succeeded = append(succeeded, j.updated...)
+	}
+	if len(failed) == 0 {
+		j.created, failed = p.applyEach(ctx, plan.Zone, toCreate, p.addDNSEntry)
+		succeeded = append(succeeded, j.created...)
+	}
+
+	if len(failed) > 0 {
+		batchErr := &BatchError{Succeeded: append(append([]libdns.Record(nil), j.deleted...), succeeded...), Failed: failed}
+		if p.Transactional {
+			return nil, p.rollback(ctx, plan.Zone, batchErr, j)
+		}
+		return succeeded, batchErr
+	}
+
+	return succeeded, nil
+}
+
+// managedByDigitalOcean reports whether record is one DigitalOcean creates and
+// maintains for every zone: the SOA record and the NS records at the apex.
+func managedByDigitalOcean(record libdns.Record) bool {
+	rr := record.RR()
+	return rr.Type == "SOA" || (rr.Type == "NS" && (rr.Name == "@" || rr.Name == ""))
+}
+
+// clampTTL returns the TTL DigitalOcean ends up using for a record with the
+// given TTL.
+func clampTTL(ttl time.Duration) time.Duration {
+	switch {
+	case ttl == 0:
+		return defaultTTL
+	case ttl < minTTL:
+		return minTTL
+	default:
+		return ttl
+	}
+}
@@ -0,0 +1,136 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+)
+
+func TestPlan_Plan(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 1, Type: "SOA", Name: "@", Data: "1800", TTL: 1800},
+		{ID: 2, Type: "NS", Name: "@", Data: "ns1.digitalocean.com", TTL: 1800},
+		{ID: 3, Type: "A", Name: "@", Data: "192.168.1.1", TTL: 3600},
+		{ID: 4, Type: "A", Name: "www", Data: "192.168.1.2", TTL: 3600},
+		{ID: 5, Type: "A", Name: "old", Data: "192.168.1.3", TTL: 3600},
+		{ID: 6, Type: "TXT", Name: "@", Data: "v=spf1 -all", TTL: 30},
+	}
+
+	p := setupTest(mockRecords, nil)
+	ctx := context.Background()
+
+	plan, err := p.Plan(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "A", Name: "", Data: "192.168.1.1", TTL: 3600 * time.Second},
+		libdns.RR{Type: "A", Name: "www.example.com.", Data: "192.168.1.20", TTL: 3600 * time.Second},
+		libdns.RR{Type: "TXT", Name: "@", Data: "v=spf1 -all", TTL: time.Second},
+		libdns.RR{Type: "MX", Name: "@", Data: "10 mail", TTL: 0},
+		libdns.RR{Type: "NS", Name: "@", Data: "ns.example.net."},
+	})
+	if err != nil {
+		t.Fatalf("Provider.Plan() error = %v", err)
+	}
+
+	want := []struct {
+		op   ChangeOp
+		id   int
+		name string
+		typ  string
+	}{
+		{ChangeDelete, 5, "old", "A"},
+		{ChangeUpdate, 4, "www", "A"},
+		{ChangeCreate, 0, "@", "MX"},
+	}
+	if len(plan.Changes) != len(want) {
+		t.Fatalf("Provider.Plan() = %v, want %d changes", plan.Changes, len(want))
+	}
+	for i, change := range plan.Changes {
+		rr := change.Record.RR()
+		if change.Op != want[i].op || change.ID != want[i].id || rr.Name != want[i].name || rr.Type != want[i].typ {
+			t.Errorf("Provider.Plan()[%d] = %v, want %s of %s %s", i, change, want[i].op, want[i].typ, want[i].name)
+		}
+	}
+
+	// The apex TXT record's TTL is clamped to the minimum and matches, the MX
+	// record gets the default TTL
+	if ttl := plan.Changes[2].Record.RR().TTL; ttl != defaultTTL {
+		t.Errorf("Provider.Plan() MX TTL = %s, want %s", ttl, defaultTTL)
+	}
+	if previous := plan.Changes[1].Previous; previous == nil || previous.RR().Data != "192.168.1.2" {
+		t.Errorf("Provider.Plan() update Previous = %v, want the current record", previous)
+	}
+	if changes := plan.ByRRSet()[RRSet{Name: "www", Type: "A"}]; len(changes) != 1 {
+		t.Errorf("Plan.ByRRSet() = %v, want one change for www A", changes)
+	}
+
+	// Test error case
+	p = setupTest(nil, errors.New("API error"))
+	if _, err := p.Plan(ctx, "example.com.", nil); err == nil {
+		t.Error("Provider.Plan() expected error, got nil")
+	}
+}
+
+func TestPlan_Apply(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 4, Type: "A", Name: "www", Data: "192.168.1.2", TTL: 3600},
+		{ID: 5, Type: "A", Name: "old", Data: "192.168.1.3", TTL: 3600},
+	}
+
+	p := setupTest(mockRecords, nil)
+	mock := p.client.Domains.(*mockDomainsService)
+	ctx := context.Background()
+
+	plan, err := p.Plan(ctx, "example.com", []libdns.Record{
+		libdns.RR{Type: "A", Name: "www", Data: "192.168.1.20", TTL: 3600 * time.Second},
+		libdns.RR{Type: "A", Name: "new", Data: "192.168.1.4", TTL: 3600 * time.Second},
+	})
+	if err != nil {
+		t.Fatalf("Provider.Plan() error = %v", err)
+	}
+
+	applied, err := p.Apply(ctx, plan)
+	if err != nil {
+		t.Fatalf("Provider.Apply() error = %v", err)
+	}
+	if len(applied) != 2 || recordID(applied[0]) != 4 || recordID(applied[1]) != 12345 {
+		t.Errorf("Provider.Apply() = %v, want the edited and created records", applied)
+	}
+	if len(mock.deleted) != 1 || mock.deleted[0] != 5 || len(mock.edited) != 1 || mock.edited[0] != 4 {
+		t.Errorf("Provider.Apply() deleted = %v, edited = %v, want [5] and [4]", mock.deleted, mock.edited)
+	}
+
+	// A failed edit stops the plan before anything is created
+	p = setupTest(mockRecords, nil)
+	mock = p.client.Domains.(*mockDomainsService)
+	mock.failData = "192.168.1.20"
+
+	applied, err = p.Apply(ctx, plan)
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 {
+		t.Fatalf("Provider.Apply() error = %v, want *BatchError", err)
+	}
+	if len(applied) != 0 {
+		t.Errorf("Provider.Apply() = %v, want nothing created", applied)
+	}
+}
+
+func TestPlan_clampTTL(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want time.Duration
+	}{
+		{0, defaultTTL},
+		{time.Second, minTTL},
+		{minTTL, minTTL},
+		{time.Hour, time.Hour},
+	}
+
+	for _, tt := range tests {
+		if got := clampTTL(tt.ttl); got != tt.want {
+			t.Errorf("clampTTL(%s) = %s, want %s", tt.ttl, got, tt.want)
+		}
+	}
+}
@@ -2,16 +2,75 @@ package digitalocean
 
 import (
 	"context"
+	"log/slog"
+	"net/http"
 	"strings"
+	"time"
 
 	"github.com/libdns/libdns"
+	"golang.org/x/oauth2"
 )
 
-// Provider implements the libdns interfaces for DigitalOcean
+// Provider implements the libdns interfaces for DigitalOcean. In JSON, its
+// durations are given as strings such as "5m" or as a number of seconds.
 type Provider struct {
 	Client
 	// APIToken is the DigitalOcean API token - see https://www.digitalocean.com/docs/apis-clis/api/create-personal-access-token/
 	APIToken string `json:"auth_token"`
+	// TokenEnv, TokenFile and TokenCommand read the API token from an environment variable, a file or the output
+	// of a command instead, every time a request is made, so rotated tokens are picked up. The file is only read
+	// again when it changes, and the command is run again after TokenCommandRefresh (default 5 minutes). The
+	// command is killed if it runs longer than TokenCommandTimeout (default 30 seconds).
+	TokenEnv            string        `json:"token_env,omitempty"`
+	TokenFile           string        `json:"token_file,omitempty"`
+	TokenCommand        []string      `json:"token_command,omitempty"`
+	TokenCommandRefresh time.Duration `json:"token_command_refresh,omitempty"`
+	TokenCommandTimeout time.Duration `json:"token_command_timeout,omitempty"`
+	// TokenSource supplies the API token programmatically. It takes precedence over all other token settings.
+	TokenSource oauth2.TokenSource `json:"-"`
+
+	// HTTPClient is used to reach the DigitalOcean API, for example to set timeouts, a proxy or client
+	// certificates. Its transport is wrapped to add the API token. Defaults to a client using
+	// http.DefaultTransport.
+	HTTPClient *http.Client `json:"-"`
+	// BaseURL is the URL of the DigitalOcean API. Defaults to https://api.digitalocean.com/.
+	BaseURL string `json:"base_url,omitempty"`
+	// UserAgent is appended to the User-Agent header sent with every request.
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// Logger receives a message for every DigitalOcean API call, with the zone, record, HTTP status, request ID,
+	// duration and number of attempts: at debug level for calls that succeed, warn level for retries and calls
+	// DigitalOcean rejects, and error level for other failures. API tokens and TXT values that look like secrets
+	// are redacted. Nothing is logged if it is nil.
+	Logger *slog.Logger `json:"-"`
+
+	// MaxConcurrency is the maximum number of API calls a single method issues in parallel. Defaults to 4.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+	// PageSize is the number of records requested per page when listing a zone. The first page tells how many
+	// pages there are, and the others are then fetched in parallel. Defaults to and is capped at 200, the most
+	// DigitalOcean returns.
+	PageSize int `json:"page_size,omitempty"`
+
+	// MaxRetries is the number of times a request failing with 429 or 5xx is retried. Requests creating records
+	// or zones are only retried on 429, as a 5xx may come after the creation. Defaults to 5, a negative value
+	// disables retries.
+	MaxRetries int `json:"max_retries,omitempty"`
+	// RetryMinWait and RetryMaxWait bound the exponential backoff between retries. Default to 1s and 30s.
+	RetryMinWait time.Duration `json:"retry_min_wait,omitempty"`
+	RetryMaxWait time.Duration `json:"retry_max_wait,omitempty"`
+	// RateLimitReserve is the number of remaining requests in the DigitalOcean rate limit below which requests are
+	// spaced out until the limit resets. Defaults to 10, a negative value disables throttling.
+	RateLimitReserve int `json:"rate_limit_reserve,omitempty"`
+
+	// CacheTTL enables caching the records of zones for the given time, so GetRecords does not read the whole
+	// zone from DigitalOcean every time. Changes made through the Provider update the cache. SetRecords,
+	// DeleteRecords and Plan always read the records they change from DigitalOcean. See also WithoutCache and
+	// RefreshZone.
+	CacheTTL time.Duration `json:"cache_ttl,omitempty"`
+
+	// Transactional makes AppendRecords, SetRecords and DeleteRecords roll back the changes they already made when
+	// part of a batch fails, and return a *RollbackError. Recreated records get new IDs.
+	Transactional bool `json:"transactional,omitempty"`
 }
 
 // unFQDN trims any trailing "." from fqdn. DigitalOcean's API does not use FQDNs.
@@ -19,7 +78,9 @@ func (p *Provider) unFQDN(fqdn string) string {
 	return strings.TrimSuffix(fqdn, ".")
 }
 
-// GetRecords lists all the records in the zone.
+// GetRecords lists all the records in the zone. Records are returned as their
+// typed libdns equivalents (libdns.Address, libdns.TXT, libdns.MX, ...), with
+// the DigitalOcean record ID available through RecordID.
 func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
 	records, err := p.getDNSEntries(ctx, p.unFQDN(zone))
 	if err != nil {
@@ -30,57 +91,340 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 }
 
 // AppendRecords adds records to the zone. It returns the records that were added.
+// Records are created in parallel, bounded by MaxConcurrency. If some records
+// could not be created, the records that were are returned along with a
+// *BatchError.
 func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	var appendedRecords []libdns.Record
+	zone = p.unFQDN(zone)
 
-	for _, record := range records {
-		newRecord, err := p.addDNSEntry(ctx, p.unFQDN(zone), record)
-		if err != nil {
-			return nil, err
+	unlock := p.lockZone(zone)
+	defer unlock()
+
+	appendedRecords, failed := p.applyEach(ctx, zone, records, p.addDNSEntry)
+	if len(failed) > 0 {
+		batchErr := &BatchError{Succeeded: appendedRecords, Failed: failed}
+		if p.Transactional {
+			return nil, p.rollback(ctx, zone, batchErr, journal{created: appendedRecords})
 		}
-		appendedRecords = append(appendedRecords, newRecord)
+		return appendedRecords, batchErr
 	}
 
 	return appendedRecords, nil
 }
 
-// DeleteRecords deletes the records from the zone.
+// DeleteRecords deletes the records in the zone that match the input records.
+// Following the libdns conventions, an empty Type, a zero TTL or empty Data in
+// an input record match any value. Input records that do not exist in the zone
+// are silently ignored. It returns the records that were deleted. If some
+// records could not be deleted, the records that were are returned along with
+// a *BatchError.
 func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	var deletedRecords []libdns.Record
+	zone = p.unFQDN(zone)
 
-	for _, record := range records {
-		deletedRecord, err := p.removeDNSEntry(ctx, p.unFQDN(zone), record)
+	unlock := p.lockZone(zone)
+	defer unlock()
+
+	normalized := make([]libdns.Record, len(records))
+	keys := make([]RRSet, len(records))
+	for i, record := range records {
+		record, err := normalizeRecord(zone, record)
 		if err != nil {
-			return nil, err
+			return nil, RecordError{Record: record, Err: err}
 		}
-		deletedRecords = append(deletedRecords, deletedRecord)
+		normalized[i] = record
+		rr := record.RR()
+		keys[i] = RRSet{Name: rr.Name, Type: rr.Type}
+	}
+
+	existing, err := p.lookupRRSets(ctx, zone, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var toDelete []libdns.Record
+	matched := make([]bool, len(existing))
+
+	for _, record := range normalized {
+		for i, entry := range existing {
+			if matched[i] || !matchRecord(record, entry) {
+				continue
+			}
+			matched[i] = true
+			toDelete = append(toDelete, entry)
+		}
+	}
+
+	deletedRecords, failed := p.applyEach(ctx, zone, toDelete, p.removeDNSEntry)
+	if len(failed) > 0 {
+		batchErr := &BatchError{Succeeded: deletedRecords, Failed: failed}
+		if p.Transactional {
+			return nil, p.rollback(ctx, zone, batchErr, journal{deleted: deletedRecords})
+		}
+		return deletedRecords, batchErr
 	}
 
 	return deletedRecords, nil
 }
 
-// SetRecords sets the records in the zone, either by updating existing records
-// or creating new ones. It returns the updated records.
+// SetRecords sets the records in the zone. For every (name, type) pair in the
+// input, existing records are edited in place where possible, missing records
+// are created and any leftover records of that pair are deleted, so that the
+// input records are the only members of their RRset afterwards. Leftover
+// records of an RRset are only deleted once all its edits and creations
+// succeeded. It returns the records that were set, along with a *BatchError if
+// some of the changes failed.
 func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	var setRecords []libdns.Record
+	zone = p.unFQDN(zone)
 
-	for _, record := range records {
-		// TODO: if there is no ID, look up the Name, and fill it in, or call
-		//       newRecord, err := p.addDNSEntry(ctx, zone, record)
-		setRecord, err := p.updateDNSEntry(ctx, p.unFQDN(zone), record)
+	unlock := p.lockZone(zone)
+	defer unlock()
+
+	// TTLs are clamped the way DigitalOcean stores them, so they compare equal
+	normalized := make([]libdns.Record, len(records))
+	for i, record := range records {
+		rr, err := normalizeRR(zone, record.RR())
 		if err != nil {
-			return setRecords, err
+			return nil, RecordError{Record: record, Err: err}
+		}
+		rr.TTL = clampTTL(rr.TTL)
+		normalized[i] = rr
+		if id, err := idFromRecord(record); err == nil {
+			normalized[i] = withID(rr, id)
 		}
-		setRecords = append(setRecords, setRecord)
+	}
+	keys := rrsetKeys(normalized)
+
+	existing, err := p.lookupRRSets(ctx, zone, keys)
+	if err != nil {
+		return nil, err
+	}
+
+	var unchanged, toUpdate, toCreate, toRemove []libdns.Record
+	for _, key := range keys {
+		diff := diffRRSet(key.filter(existing), key.filter(normalized))
+		unchanged = append(unchanged, diff.unchanged...)
+		toUpdate = append(toUpdate, diff.update...)
+		toCreate = append(toCreate, diff.create...)
+		toRemove = append(toRemove, diff.remove...)
+	}
+
+	updated, failed := p.applyEach(ctx, zone, toUpdate, p.updateDNSEntry)
+	created, createFailed := p.applyEach(ctx, zone, toCreate, p.addDNSEntry)
+	failed = append(failed, createFailed...)
+
+	setRecords := append(append(unchanged, updated...), created...)
+
+	j := journal{snapshot: snapshotOf(existing), created: created, updated: updated}
+	if p.Transactional && len(failed) > 0 {
+		return nil, p.rollback(ctx, zone, &BatchError{Succeeded: setRecords, Failed: failed}, j)
+	}
+
+	// keep the leftovers of RRsets that could not be set completely
+	incomplete := make(map[RRSet]bool)
+	for _, f := range failed {
+		rr := f.Record.RR()
+		incomplete[RRSet{Name: rr.Name, Type: rr.Type}] = true
+	}
+	var removable []libdns.Record
+	for _, record := range toRemove {
+		rr := record.RR()
+		if !incomplete[RRSet{Name: rr.Name, Type: rr.Type}] {
+			removable = append(removable, record)
+		}
+	}
+
+	removed, removeFailed := p.applyEach(ctx, zone, removable, p.removeDNSEntry)
+	failed = append(failed, removeFailed...)
+
+	if len(failed) > 0 {
+		batchErr := &BatchError{Succeeded: setRecords, Failed: failed}
+		if p.Transactional {
+			j.deleted = removed
+			return nil, p.rollback(ctx, zone, batchErr, j)
+		}
+		return setRecords, batchErr
 	}
 
 	return setRecords, nil
 }
 
+// applyEach calls fn for each of the records in parallel, bounded by
+// MaxConcurrency. It returns the results of the successful calls in input
+// order, and the records for which fn failed.
+func (p *Provider) applyEach(ctx context.Context, zone string, records []libdns.Record,
+	fn func(ctx context.Context, zone string, record libdns.Record) (libdns.Record, error)) ([]libdns.Record, []RecordError) {
+	results := make([]libdns.Record, len(records))
+	errs := make([]error, len(records))
+
+	p.forEach(ctx, len(records), func(ctx context.Context, i int) {
+		results[i], errs[i] = fn(ctx, zone, records[i])
+	})
+
+	var succeeded []libdns.Record
+	var failed []RecordError
+	for i, record := range records {
+		if errs[i] != nil {
+			failed = append(failed, RecordError{Record: record, Err: errs[i]})
+		} else {
+			succeeded = append(succeeded, results[i])
+		}
+	}
+
+	return succeeded, failed
+}
+
+// ListZones lists all the zones (domains) in the DigitalOcean account. Zone
+// names are returned fully qualified, with a trailing dot.
+func (p *Provider) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+	zones, err := p.getZones(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return zones, nil
+}
+
+// RRSet identifies a set of records by their (name, type) pair. Names are
+// relative to the zone, with "@" for the apex.
+type RRSet struct {
+	Name string
+	Type string
+}
+
+// rrsetKeys returns the distinct (name, type) pairs of records, in the order
+// they first appear.
+func rrsetKeys(records []libdns.Record) []RRSet {
+	var keys []RRSet
+	seen := make(map[RRSet]bool)
+	for _, record := range records {
+		rr := record.RR()
+		key := RRSet{Name: rr.Name, Type: rr.Type}
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// filter returns the records that belong to the RRset identified by k.
+func (k RRSet) filter(records []libdns.Record) []libdns.Record {
+	var matched []libdns.Record
+	for _, record := range records {
+		rr := record.RR()
+		if rr.Name == k.Name && rr.Type == k.Type {
+			matched = append(matched, record)
+		}
+	}
+	return matched
+}
+
+// matchRecord reports whether the existing record have matches want. Empty
+// Type, zero TTL and empty Data in want act as wildcards. If want carries a
+// DigitalOcean ID, only the record with that ID matches, and the name may be
+// left empty as well.
+func matchRecord(want, have libdns.Record) bool {
+	wantRR, haveRR := want.RR(), have.RR()
+
+	if wantID, err := idFromRecord(want); err == nil {
+		if haveID, err := idFromRecord(have); err != nil || haveID != wantID {
+			return false
+		}
+		if wantRR.Name != "" && wantRR.Name != haveRR.Name {
+			return false
+		}
+	} else if wantRR.Name != haveRR.Name {
+		return false
+	}
+
+	if wantRR.Type != "" && wantRR.Type != haveRR.Type {
+		return false
+	}
+	if wantRR.TTL != 0 && wantRR.TTL != haveRR.TTL {
+		return false
+	}
+	if wantRR.Data != "" && wantRR.Data != haveRR.Data {
+		return false
+	}
+
+	return true
+}
+
+// rrsetDiff describes the changes needed to turn one RRset into another.
+// Records in unchanged and update carry the ID of the existing record they
+// correspond to.
+type rrsetDiff struct {
+	unchanged []libdns.Record
+	update    []libdns.Record
+	create    []libdns.Record
+	remove    []libdns.Record
+}
+
+// diffRRSet computes the changes needed to make existing (records read from
+// DigitalOcean, carrying IDs) match desired. Records with identical data are
+// reused first, then remaining existing records are edited in place before
+// anything is created or deleted.
+func diffRRSet(existing, desired []libdns.Record) rrsetDiff {
+	var diff rrsetDiff
+
+	used := make([]bool, len(existing))
+	var pending []libdns.Record
+
+	for _, want := range desired {
+		wantRR := want.RR()
+		matched := false
+		for i, have := range existing {
+			if used[i] || have.RR().Data != wantRR.Data {
+				continue
+			}
+			used[i] = true
+			matched = true
+
+			id, _ := idFromRecord(have)
+			if have.RR().TTL == wantRR.TTL {
+				diff.unchanged = append(diff.unchanged, have)
+			} else {
+				diff.update = append(diff.update, withID(want, id))
+			}
+			break
+		}
+		if !matched {
+			pending = append(pending, want)
+		}
+	}
+
+	for _, want := range pending {
+		reused := false
+		for i, have := range existing {
+			if used[i] {
+				continue
+			}
+			used[i] = true
+			reused = true
+
+			id, _ := idFromRecord(have)
+			diff.update = append(diff.update, withID(want, id))
+			break
+		}
+		if !reused {
+			diff.create = append(diff.create, want)
+		}
+	}
+
+	for i, have := range existing {
+		if !used[i] {
+			diff.remove = append(diff.remove, have)
+		}
+	}
+
+	return diff
+}
+
 // Interface guards
 var (
 	_ libdns.RecordGetter   = (*Provider)(nil)
 	_ libdns.RecordAppender = (*Provider)(nil)
 	_ libdns.RecordSetter   = (*Provider)(nil)
 	_ libdns.RecordDeleter  = (*Provider)(nil)
+	_ libdns.ZoneLister     = (*Provider)(nil)
 )
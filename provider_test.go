@@ -3,27 +3,68 @@ package digitalocean
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"net/netip"
+	"sort"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/digitalocean/godo"
 	"github.com/libdns/libdns"
+	"github.com/wzzrd/libdns-digitalocean/digitaloceantest"
 )
 
 // mockDomainsService is a mock implementation of godo.DomainsService
 type mockDomainsService struct {
 	// Mock return data
+	domains []godo.Domain
 	records []godo.DomainRecord
 	record  *godo.DomainRecord
 
 	// Error to return (when testing error paths)
 	err error
+
+	// Data of the records for which creating or editing fails
+	failData string
+
+	// IDs of the records that were edited or deleted, and the type and name
+	// of the filtered lookups that were made
+	mu      sync.Mutex
+	edited  []int
+	deleted []int
+	lookups []string
 }
 
 func (m *mockDomainsService) List(ctx context.Context, opts *godo.ListOptions) ([]godo.Domain, *godo.Response, error) {
-	// Not used in our tests
-	return nil, nil, nil
+	if m.err != nil {
+		return nil, &godo.Response{Response: &http.Response{StatusCode: 500}}, m.err
+	}
+
+	resp := &godo.Response{
+		Response: &http.Response{StatusCode: 200},
+		Links:    &godo.Links{},
+	}
+
+	// Simulate pagination by returning one domain per page
+	page := 1
+	if opts != nil && opts.Page > 1 {
+		page = opts.Page
+	}
+	if page > len(m.domains) {
+		return []godo.Domain{}, resp, nil
+	}
+	if page < len(m.domains) {
+		resp.Links.Pages = &godo.Pages{
+			Next: fmt.Sprintf("https://api.digitalocean.com/v2/domains?page=%d", page+1),
+		}
+		if page > 1 {
+			resp.Links.Pages.Prev = fmt.Sprintf("https://api.digitalocean.com/v2/domains?page=%d", page-1)
+		}
+	}
+
+	return m.domains[page-1 : page], resp, nil
 }
 
 func (m *mockDomainsService) Get(ctx context.Context, name string) (*godo.Domain, *godo.Response, error) {
@@ -47,18 +88,41 @@ func (m *mockDomainsService) Record(ctx context.Context, domain string, id int)
 }
 
 func (m *mockDomainsService) RecordsByType(ctx context.Context, domain string, ofType string, opt *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error) {
-	// Not used in our tests
-	return nil, nil, nil
+	return m.filterRecords(ctx, domain, ofType, "", opt)
 }
 
 func (m *mockDomainsService) RecordsByName(ctx context.Context, domain, name string, opt *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error) {
-	// Not used in our tests
-	return nil, nil, nil
+	return m.filterRecords(ctx, domain, "", name, opt)
 }
 
 func (m *mockDomainsService) RecordsByTypeAndName(ctx context.Context, domain, ofType, name string, opt *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error) {
-	// Not used in our tests
-	return nil, nil, nil
+	return m.filterRecords(ctx, domain, ofType, name, opt)
+}
+
+// filterRecords returns the records with the given type and fully qualified
+// name, either of which may be empty, like the API does
+func (m *mockDomainsService) filterRecords(ctx context.Context, domain, ofType, name string, opt *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error) {
+	m.mu.Lock()
+	m.lookups = append(m.lookups, ofType+" "+name)
+	m.mu.Unlock()
+
+	records, resp, err := m.Records(ctx, domain, opt)
+	if err != nil {
+		return records, resp, err
+	}
+
+	var filtered []godo.DomainRecord
+	for _, record := range records {
+		fqdn := record.Name + "." + domain
+		if record.Name == "@" {
+			fqdn = domain
+		}
+		if (ofType == "" || record.Type == ofType) && (name == "" || fqdn == name) {
+			filtered = append(filtered, record)
+		}
+	}
+
+	return filtered, resp, nil
 }
 
 func (m *mockDomainsService) Records(ctx context.Context, domain string, opts *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error) {
@@ -84,12 +148,21 @@ func (m *mockDomainsService) CreateRecord(ctx context.Context, domain string, cr
 		return nil, &godo.Response{Response: &http.Response{StatusCode: 500}}, m.err
 	}
 
+	if createRequest.Data == m.failData {
+		return nil, &godo.Response{Response: &http.Response{StatusCode: 422}}, errors.New("invalid record")
+	}
+
 	record := &godo.DomainRecord{
-		ID:   12345,
-		Type: createRequest.Type,
-		Name: createRequest.Name,
-		Data: createRequest.Data,
-		TTL:  createRequest.TTL,
+		ID:       12345,
+		Type:     createRequest.Type,
+		Name:     createRequest.Name,
+		Data:     createRequest.Data,
+		Priority: createRequest.Priority,
+		Port:     createRequest.Port,
+		TTL:      createRequest.TTL,
+		Weight:   createRequest.Weight,
+		Flags:    createRequest.Flags,
+		Tag:      createRequest.Tag,
 	}
 
 	return record, &godo.Response{Response: &http.Response{StatusCode: 201}}, nil
@@ -100,6 +173,10 @@ func (m *mockDomainsService) DeleteRecord(ctx context.Context, domain string, id
 		return &godo.Response{Response: &http.Response{StatusCode: 500}}, m.err
 	}
 
+	m.mu.Lock()
+	m.deleted = append(m.deleted, id)
+	m.mu.Unlock()
+
 	return &godo.Response{Response: &http.Response{StatusCode: 204}}, nil
 }
 
@@ -108,12 +185,25 @@ func (m *mockDomainsService) EditRecord(ctx context.Context, domain string, id i
 		return nil, &godo.Response{Response: &http.Response{StatusCode: 500}}, m.err
 	}
 
+	if editRequest.Data == m.failData {
+		return nil, &godo.Response{Response: &http.Response{StatusCode: 422}}, errors.New("invalid record")
+	}
+
+	m.mu.Lock()
+	m.edited = append(m.edited, id)
+	m.mu.Unlock()
+
 	record := &godo.DomainRecord{
-		ID:   id,
-		Type: editRequest.Type,
-		Name: editRequest.Name,
-		Data: editRequest.Data,
-		TTL:  editRequest.TTL,
+		ID:       id,
+		Type:     editRequest.Type,
+		Name:     editRequest.Name,
+		Data:     editRequest.Data,
+		Priority: editRequest.Priority,
+		Port:     editRequest.Port,
+		TTL:      editRequest.TTL,
+		Weight:   editRequest.Weight,
+		Flags:    editRequest.Flags,
+		Tag:      editRequest.Tag,
 	}
 
 	return record, &godo.Response{Response: &http.Response{StatusCode: 200}}, nil
@@ -132,6 +222,15 @@ type mockClient struct {
 	Domains mockDomainsService
 }
 
+// recordID returns the DigitalOcean ID of record, or -1 if it has none
+func recordID(record libdns.Record) int {
+	id, ok := RecordID(record)
+	if !ok {
+		return -1
+	}
+	return id
+}
+
 // setupTest creates a Provider with a mock DigitalOcean client
 func setupTest(records []godo.DomainRecord, err error) *Provider {
 	mock := &mockDomainsService{
@@ -141,6 +240,9 @@ func setupTest(records []godo.DomainRecord, err error) *Provider {
 
 	provider := &Provider{
 		APIToken: "test-token",
+		// Keep retries of failing calls quick
+		RetryMinWait: time.Millisecond,
+		RetryMaxWait: time.Millisecond,
 	}
 
 	// Set the client directly
@@ -220,13 +322,13 @@ func TestProvider_GetRecords(t *testing.T) {
 
 	// Verify first record
 	if records[0].RR().Type != "A" || records[0].RR().Name != "test" ||
-		records[0].RR().Data != "192.168.1.1" || records[0].(DNS).ID != "1" {
+		records[0].RR().Data != "192.168.1.1" || recordID(records[0]) != 1 {
 		t.Errorf("Provider.GetRecords()[0] = %v, want A record", records[0])
 	}
 
 	// Verify second record
 	if records[1].RR().Type != "CNAME" || records[1].RR().Name != "www" ||
-		records[1].RR().Data != "example.com" || records[1].(DNS).ID != "2" {
+		records[1].RR().Data != "example.com." || recordID(records[1]) != 2 {
 		t.Errorf("Provider.GetRecords()[1] = %v, want CNAME record", records[1])
 	}
 
@@ -266,7 +368,7 @@ func TestProvider_AppendRecords(t *testing.T) {
 	if appendedRecords[0].RR().Type != testRecord.RR().Type ||
 		appendedRecords[0].RR().Name != testRecord.RR().Name ||
 		appendedRecords[0].RR().Data != testRecord.RR().Data ||
-		appendedRecords[0].(DNS).ID != "12345" {
+		recordID(appendedRecords[0]) != 12345 {
 		t.Errorf("Provider.AppendRecords() record mismatch, got = %v, want Type=%s, Name=%s, Data=%s, ID=12345",
 			appendedRecords[0], testRecord.RR().Type, testRecord.RR().Name, testRecord.RR().Data)
 	}
@@ -280,20 +382,75 @@ func TestProvider_AppendRecords(t *testing.T) {
 	}
 }
 
+func TestProvider_AppendRecordsPartialFailure(t *testing.T) {
+	testRecords := []libdns.Record{
+		libdns.RR{Type: "A", Name: "one", Data: "192.168.1.1"},
+		libdns.RR{Type: "A", Name: "two", Data: "192.168.1.2"},
+		libdns.RR{Type: "A", Name: "three", Data: "192.168.1.3"},
+	}
+
+	p := setupTest(nil, nil)
+	p.client.Domains.(*mockDomainsService).failData = "192.168.1.2"
+	ctx := context.Background()
+
+	appendedRecords, err := p.AppendRecords(ctx, "example.com.", testRecords)
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Provider.AppendRecords() error = %v, want *BatchError", err)
+	}
+
+	// The successful records are returned alongside the error, with their IDs
+	if len(appendedRecords) != 2 || len(batchErr.Succeeded) != 2 {
+		t.Fatalf("Provider.AppendRecords() returned %d records, want 2", len(appendedRecords))
+	}
+	for _, record := range batchErr.Succeeded {
+		if recordID(record) != 12345 {
+			t.Errorf("Provider.AppendRecords() succeeded record %v has no ID", record)
+		}
+	}
+
+	if len(batchErr.Failed) != 1 || batchErr.Failed[0].Record.RR().Name != "two" {
+		t.Errorf("Provider.AppendRecords() failed = %v, want record two", batchErr.Failed)
+	}
+}
+
 func TestProvider_DeleteRecords(t *testing.T) {
-	// Test record to delete
-	testRecord := DNS{
-		ID: "1",
-		Record: libdns.RR{
+	// Existing records in the zone
+	mockRecords := []godo.DomainRecord{
+		{
+			ID:   1,
 			Type: "A",
 			Name: "test",
 			Data: "192.168.1.1",
-			TTL:  3600 * time.Second,
+			TTL:  3600,
+		},
+		{
+			ID:   2,
+			Type: "TXT",
+			Name: "test",
+			Data: "hello world",
+			TTL:  1800,
+		},
+		{
+			ID:   3,
+			Type: "A",
+			Name: "other",
+			Data: "192.168.1.1",
+			TTL:  3600,
 		},
 	}
 
+	// Test record to delete, matched by content only
+	testRecord := libdns.RR{
+		Type: "A",
+		Name: "test",
+		Data: "192.168.1.1",
+		TTL:  3600 * time.Second,
+	}
+
 	// Test successful call
-	p := setupTest(nil, nil)
+	p := setupTest(mockRecords, nil)
 	ctx := context.Background()
 
 	deletedRecords, err := p.DeleteRecords(ctx, "example.com.", []libdns.Record{testRecord})
@@ -303,12 +460,52 @@ func TestProvider_DeleteRecords(t *testing.T) {
 	}
 
 	if len(deletedRecords) != 1 {
-		t.Errorf("Provider.DeleteRecords() returned %d records, want 1", len(deletedRecords))
+		t.Fatalf("Provider.DeleteRecords() returned %d records, want 1", len(deletedRecords))
 	}
 
 	// Verify the returned record
-	if deletedRecords[0].(DNS).ID != testRecord.ID {
-		t.Errorf("Provider.DeleteRecords() record ID mismatch, got = %v, want = %v", deletedRecords[0].(DNS).ID, testRecord.ID)
+	if recordID(deletedRecords[0]) != 1 {
+		t.Errorf("Provider.DeleteRecords() record ID mismatch, got = %v, want = 1", recordID(deletedRecords[0]))
+	}
+
+	// Test wildcard matching: only the name is given
+	p = setupTest(mockRecords, nil)
+
+	deletedRecords, err = p.DeleteRecords(ctx, "example.com.", []libdns.Record{libdns.RR{Name: "test"}})
+	if err != nil {
+		t.Errorf("Provider.DeleteRecords() error = %v", err)
+	}
+
+	mock := p.client.Domains.(*mockDomainsService)
+	sort.Ints(mock.deleted)
+	if len(deletedRecords) != 2 || len(mock.deleted) != 2 || mock.deleted[0] != 1 || mock.deleted[1] != 2 {
+		t.Errorf("Provider.DeleteRecords() deleted = %v, want [1 2]", mock.deleted)
+	}
+
+	// Test deletion by ID
+	p = setupTest(mockRecords, nil)
+
+	deletedRecords, err = p.DeleteRecords(ctx, "example.com.", []libdns.Record{DNS{ID: "3"}})
+	if err != nil {
+		t.Errorf("Provider.DeleteRecords() error = %v", err)
+	}
+	if len(deletedRecords) != 1 || recordID(deletedRecords[0]) != 3 {
+		t.Errorf("Provider.DeleteRecords() = %v, want record with ID 3", deletedRecords)
+	}
+
+	// Records that do not exist are silently ignored
+	p = setupTest(mockRecords, nil)
+
+	deletedRecords, err = p.DeleteRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "A", Name: "test", Data: "192.168.1.2"},
+		libdns.RR{Type: "TXT", Name: "test", Data: "hello world", TTL: 3600 * time.Second},
+		DNS{ID: "invalid"},
+	})
+	if err != nil {
+		t.Errorf("Provider.DeleteRecords() error = %v", err)
+	}
+	if len(deletedRecords) != 0 {
+		t.Errorf("Provider.DeleteRecords() returned %d records, want 0", len(deletedRecords))
 	}
 
 	// Test error case
@@ -318,79 +515,290 @@ func TestProvider_DeleteRecords(t *testing.T) {
 	if err == nil {
 		t.Error("Provider.DeleteRecords() expected error, got nil")
 	}
+}
 
-	// Test error case with invalid ID
-	p = setupTest(nil, nil)
-
-	invalidIDRecord := DNS{
-		ID: "invalid", // Non-numeric ID
-		Record: libdns.RR{
+func TestProvider_SetRecords(t *testing.T) {
+	// Existing records in the zone
+	mockRecords := []godo.DomainRecord{
+		{
+			ID:   1,
 			Type: "A",
 			Name: "test",
 			Data: "192.168.1.1",
+			TTL:  3600,
+		},
+		{
+			ID:   2,
+			Type: "A",
+			Name: "test",
+			Data: "192.168.1.3",
+			TTL:  3600,
+		},
+		{
+			ID:   3,
+			Type: "TXT",
+			Name: "test",
+			Data: "untouched",
+			TTL:  3600,
 		},
 	}
 
-	_, err = p.DeleteRecords(ctx, "example.com.", []libdns.Record{invalidIDRecord})
-	if err == nil {
-		t.Error("Provider.DeleteRecords() expected error for invalid ID, got nil")
-	}
-}
-
-func TestProvider_SetRecords(t *testing.T) {
-	// Test record to set
-	testRecord := DNS{
-		ID: "1",
-		Record: libdns.RR{
+	// Records to set, without any DigitalOcean IDs
+	testRecords := []libdns.Record{
+		libdns.RR{
 			Type: "A",
 			Name: "test",
-			Data: "192.168.1.1",
+			Data: "192.168.1.2",
+			TTL:  3600 * time.Second,
+		},
+		libdns.RR{
+			Type: "TXT",
+			Name: "new",
+			Data: "hello world",
 			TTL:  3600 * time.Second,
 		},
 	}
 
 	// Test successful call
-	p := setupTest(nil, nil)
+	p := setupTest(mockRecords, nil)
 	ctx := context.Background()
 
-	setRecords, err := p.SetRecords(ctx, "example.com.", []libdns.Record{testRecord})
+	setRecords, err := p.SetRecords(ctx, "example.com.", testRecords)
 
 	if err != nil {
 		t.Errorf("Provider.SetRecords() error = %v", err)
 	}
 
-	if len(setRecords) != 1 {
-		t.Errorf("Provider.SetRecords() returned %d records, want 1", len(setRecords))
+	if len(setRecords) != 2 {
+		t.Fatalf("Provider.SetRecords() returned %d records, want 2", len(setRecords))
 	}
 
-	// Verify the returned record
-	if setRecords[0].(DNS).ID != testRecord.ID {
-		t.Errorf("Provider.SetRecords() record ID mismatch, got = %v, want = %v", setRecords[0].(DNS).ID, testRecord.ID)
+	// The first existing A record is edited in place, the second one deleted
+	if recordID(setRecords[0]) != 1 || setRecords[0].RR().Data != "192.168.1.2" {
+		t.Errorf("Provider.SetRecords()[0] = %v, want edited record with ID 1", setRecords[0])
+	}
+
+	// The TXT record did not exist yet and is created
+	if recordID(setRecords[1]) != 12345 || setRecords[1].RR().Data != "hello world" {
+		t.Errorf("Provider.SetRecords()[1] = %v, want created record with ID 12345", setRecords[1])
+	}
+
+	mock := p.client.Domains.(*mockDomainsService)
+	if len(mock.edited) != 1 || mock.edited[0] != 1 {
+		t.Errorf("Provider.SetRecords() edited = %v, want [1]", mock.edited)
+	}
+	if len(mock.deleted) != 1 || mock.deleted[0] != 2 {
+		t.Errorf("Provider.SetRecords() deleted = %v, want [2]", mock.deleted)
+	}
+
+	// Setting records that already exist is a no-op, also when given by their absolute name
+	p = setupTest(mockRecords, nil)
+
+	setRecords, err = p.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "TXT", Name: "test.example.com.", Data: "untouched", TTL: 3600 * time.Second},
+	})
+	if err != nil {
+		t.Errorf("Provider.SetRecords() error = %v", err)
+	}
+	if len(setRecords) != 1 || recordID(setRecords[0]) != 3 {
+		t.Errorf("Provider.SetRecords() = %v, want existing record with ID 3", setRecords)
+	}
+
+	mock = p.client.Domains.(*mockDomainsService)
+	if len(mock.edited) != 0 || len(mock.deleted) != 0 {
+		t.Errorf("Provider.SetRecords() edited = %v, deleted = %v, want no changes", mock.edited, mock.deleted)
 	}
 
 	// Test error case
 	p = setupTest(nil, errors.New("API error"))
 
-	_, err = p.SetRecords(ctx, "example.com.", []libdns.Record{testRecord})
+	_, err = p.SetRecords(ctx, "example.com.", testRecords)
 	if err == nil {
 		t.Error("Provider.SetRecords() expected error, got nil")
 	}
+}
 
-	// Test error case with invalid ID
-	p = setupTest(nil, nil)
+func TestProvider_SetRecordsPartialFailure(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 1, Type: "A", Name: "one", Data: "192.168.1.1", TTL: 3600},
+		{ID: 2, Type: "A", Name: "one", Data: "192.168.1.2", TTL: 3600},
+		{ID: 3, Type: "A", Name: "two", Data: "192.168.1.3", TTL: 3600},
+		{ID: 4, Type: "A", Name: "two", Data: "192.168.1.4", TTL: 3600},
+	}
 
-	invalidIDRecord := DNS{
-		ID: "invalid", // Non-numeric ID
-		Record: libdns.RR{
-			Type: "A",
-			Name: "test",
-			Data: "192.168.1.1",
-		},
+	p := setupTest(mockRecords, nil)
+	p.client.Domains.(*mockDomainsService).failData = "192.168.1.30"
+	ctx := context.Background()
+
+	setRecords, err := p.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "A", Name: "one", Data: "192.168.1.10", TTL: 3600 * time.Second},
+		libdns.RR{Type: "A", Name: "two", Data: "192.168.1.30", TTL: 3600 * time.Second},
+	})
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Provider.SetRecords() error = %v, want *BatchError", err)
 	}
+	if len(setRecords) != 1 || recordID(setRecords[0]) != 1 {
+		t.Errorf("Provider.SetRecords() = %v, want the edited record with ID 1", setRecords)
+	}
+	if len(batchErr.Failed) != 1 || batchErr.Failed[0].Record.RR().Name != "two" {
+		t.Errorf("Provider.SetRecords() failed = %v, want record two", batchErr.Failed)
+	}
+
+	// The leftover of the RRset that was set is deleted, the other RRset is left alone
+	mock := p.client.Domains.(*mockDomainsService)
+	if len(mock.deleted) != 1 || mock.deleted[0] != 2 {
+		t.Errorf("Provider.SetRecords() deleted = %v, want [2]", mock.deleted)
+	}
+}
+
+func TestProvider_ListZones(t *testing.T) {
+	p := setupTest(nil, nil)
+	p.client.Domains.(*mockDomainsService).domains = []godo.Domain{
+		{Name: "example.com"},
+		{Name: "example.net"},
+		{Name: "example.org"},
+	}
+	ctx := context.Background()
 
-	_, err = p.SetRecords(ctx, "example.com.", []libdns.Record{invalidIDRecord})
+	zones, err := p.ListZones(ctx)
+	if err != nil {
+		t.Errorf("Provider.ListZones() error = %v", err)
+	}
+
+	want := []string{"example.com.", "example.net.", "example.org."}
+	if len(zones) != len(want) {
+		t.Fatalf("Provider.ListZones() returned %d zones, want %d", len(zones), len(want))
+	}
+	for i, zone := range zones {
+		if zone.Name != want[i] {
+			t.Errorf("Provider.ListZones()[%d] = %v, want %v", i, zone.Name, want[i])
+		}
+	}
+
+	// Test error case
+	p = setupTest(nil, errors.New("API error"))
+
+	_, err = p.ListZones(ctx)
 	if err == nil {
-		t.Error("Provider.SetRecords() expected error for invalid ID, got nil")
+		t.Error("Provider.ListZones() expected error, got nil")
+	}
+}
+
+func TestProvider_fakeServer(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+
+	if _, err := server.AddDomain("example.com",
+		godo.DomainRecord{Type: "A", Name: "www", Data: "192.168.1.1", TTL: 3600},
+		godo.DomainRecord{Type: "TXT", Name: "_acme-challenge", Data: "token", TTL: 30},
+	); err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+
+	p := &Provider{
+		APIToken:     "test-token",
+		BaseURL:      server.URL,
+		RetryMinWait: time.Millisecond,
+		RetryMaxWait: time.Millisecond,
+	}
+	ctx := context.Background()
+
+	// Transient failures are retried
+	server.Fail(http.StatusServiceUnavailable, 2)
+	records, err := p.GetRecords(ctx, "example.com.")
+	if err != nil {
+		t.Fatalf("Provider.GetRecords() error = %v", err)
+	}
+	if len(records) != 6 {
+		t.Errorf("Provider.GetRecords() returned %d records, want 6", len(records))
+	}
+
+	_, err = p.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.MX{Name: "@", Preference: 10, Target: "mail", TTL: time.Hour},
+		libdns.CNAME{Name: "www", Target: "example.com.", TTL: time.Hour},
+	})
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 || batchErr.Failed[0].Record.RR().Type != "CNAME" {
+		t.Errorf("Provider.SetRecords() error = %v, want the CNAME to conflict with the A record", err)
+	}
+
+	deleted, err := p.DeleteRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "A", Name: "www.example.com."},
+		libdns.TXT{Name: "_acme-challenge"},
+	})
+	if err != nil || len(deleted) != 2 {
+		t.Errorf("Provider.DeleteRecords() = %v, %v, want 2 records deleted", deleted, err)
+	}
+
+	var types []string
+	for _, record := range server.Records("example.com") {
+		types = append(types, record.Type+" "+record.Name+" "+record.Data)
+	}
+	want := "[SOA @ 1800 NS @ ns1.digitalocean.com NS @ ns2.digitalocean.com NS @ ns3.digitalocean.com MX @ mail.example.com.]"
+	if got := fmt.Sprint(types); got != want {
+		t.Errorf("zone holds %s, want %s", got, want)
+	}
+}
+
+func TestProvider_SetRecordsIdempotent(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+
+	if _, err := server.AddDomain("example.com",
+		godo.DomainRecord{Type: "A", Name: "@", Data: "192.168.1.1", TTL: 1800},
+		godo.DomainRecord{Type: "TXT", Name: "test", Data: "hello", TTL: 30},
+	); err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+
+	p := &Provider{APIToken: "test-token", BaseURL: server.URL}
+	ctx := context.Background()
+
+	// An empty name is the apex, and TTLs DigitalOcean raises compare equal to what it stored
+	records := []libdns.Record{
+		libdns.Address{Name: "", IP: netip.MustParseAddr("192.168.1.1")},
+		libdns.TXT{Name: "test", Text: "hello", TTL: 10 * time.Second},
+	}
+	changes := &ChangeSet{}
+	if _, err := p.SetRecords(WithDryRun(ctx, changes), "example.com.", records); err != nil {
+		t.Fatalf("Provider.SetRecords() dry run error = %v", err)
+	}
+	if got := changes.Changes(); len(got) != 0 {
+		t.Errorf("Provider.SetRecords() of the records in the zone planned changes %v, want none", got)
+	}
+
+	if _, err := p.SetRecords(ctx, "example.com.", records); err != nil {
+		t.Fatalf("Provider.SetRecords() error = %v", err)
+	}
+	if got := len(server.Records("example.com")); got != 6 {
+		t.Errorf("zone holds %d records after SetRecords(), want 6", got)
+	}
+}
+
+func TestProvider_outsideZone(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+	if _, err := server.AddDomain("example.com"); err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+
+	p := &Provider{APIToken: "test-token", BaseURL: server.URL}
+	ctx := context.Background()
+	records := []libdns.Record{libdns.TXT{Name: "foo.other.org.", Text: "hello"}}
+
+	if _, err := p.AppendRecords(ctx, "example.com.", records); !errors.Is(err, ErrOutsideZone) {
+		t.Errorf("Provider.AppendRecords() error = %v, want ErrOutsideZone", err)
+	}
+	if _, err := p.SetRecords(ctx, "example.com.", records); !errors.Is(err, ErrOutsideZone) {
+		t.Errorf("Provider.SetRecords() error = %v, want ErrOutsideZone", err)
+	}
+	if _, err := p.DeleteRecords(ctx, "example.com.", records); !errors.Is(err, ErrOutsideZone) {
+		t.Errorf("Provider.DeleteRecords() error = %v, want ErrOutsideZone", err)
+	}
+	if got := server.Requests(); got != 0 {
+		t.Errorf("Provider made %d requests for a record outside the zone, want 0", got)
 	}
 }
 
@@ -0,0 +1,183 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"time"
+
+	"github.com/digitalocean/godo"
+)
+
+// Defaults for the retry policy, used when the corresponding Provider fields are not set.
+const (
+	defaultMaxRetries       = 5
+	defaultRetryMinWait     = 1 * time.Second
+	defaultRetryMaxWait     = 30 * time.Second
+	defaultRateLimitReserve = 10
+)
+
+// do runs call, which issues a single godo request. Requests that fail with
+// 429 Too Many Requests or, unless op creates something, a 5xx status are
+// retried with jittered exponential
+// backoff, and requests are spaced out once the remaining rate limit quota
+// reported by DigitalOcean drops to RateLimitReserve. Waiting never extends
+// past the deadline of ctx. The outcome of the call and every retry are logged
+// to Logger, described by op. When op removes something, a 404 Not Found
+// after a retried 5xx counts as success, as the failed attempt may have been
+// processed.
+func (p *Provider) do(ctx context.Context, op *apiOp, call func() (*godo.Response, error)) error {
+	start := time.Now()
+	mayBeDone := false
+	for attempt := 0; ; attempt++ {
+		if err := sleep(ctx, p.throttleDelay(time.Now())); err != nil {
+			p.logCall(ctx, op, nil, err, attempt, start)
+			return err
+		}
+
+		resp, err := call()
+		p.observeRate(resp)
+		if err == nil || (op.remove && mayBeDone && notFound(resp)) {
+			p.logCall(ctx, op, resp, nil, attempt+1, start)
+			return nil
+		}
+
+		if !retryable(op, resp) || attempt >= p.maxRetries() {
+			p.logCall(ctx, op, resp, err, attempt+1, start)
+			return err
+		}
+
+		mayBeDone = mayBeDone || resp.StatusCode >= 500
+		wait := p.backoff(attempt, resp, time.Now())
+		p.logRetry(ctx, op, resp, err, attempt+1, wait)
+		if waitErr := sleep(ctx, wait); waitErr != nil {
+			err = fmt.Errorf("%w (not retried: %w)", err, waitErr)
+			p.logCall(ctx, op, resp, err, attempt+1, start)
+			return err
+		}
+	}
+}
+
+// retryable reports whether a failed request should be retried. A 5xx status
+// may come from a gateway after DigitalOcean made the change, so requests that
+// create something are only retried on 429 Too Many Requests, which means it
+// was not processed; retrying them on 5xx could create duplicates.
+func retryable(op *apiOp, resp *godo.Response) bool {
+	if resp == nil || resp.Response == nil {
+		return false
+	}
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return resp.StatusCode >= 500 && !op.create
+}
+
+// notFound reports whether resp has the status 404 Not Found.
+func notFound(resp *godo.Response) bool {
+	return resp != nil && resp.Response != nil && resp.StatusCode == http.StatusNotFound
+}
+
+func (p *Provider) maxRetries() int {
+	switch {
+	case p.MaxRetries < 0:
+		return 0
+	case p.MaxRetries == 0:
+		return defaultMaxRetries
+	default:
+		return p.MaxRetries
+	}
+}
+
+// backoff returns how long to wait before retrying after the given attempt
+// failed. When the rate limit is exhausted, it waits for the limit to reset;
+// otherwise it backs off exponentially between RetryMinWait and RetryMaxWait,
+// with the upper half of the interval jittered.
+func (p *Provider) backoff(attempt int, resp *godo.Response, now time.Time) time.Duration {
+	minWait, maxWait := p.RetryMinWait, p.RetryMaxWait
+	if minWait <= 0 {
+		minWait = defaultRetryMinWait
+	}
+	if maxWait <= 0 {
+		maxWait = defaultRetryMaxWait
+	}
+	if maxWait < minWait {
+		maxWait = minWait
+	}
+
+	if resp.StatusCode == http.StatusTooManyRequests && resp.Rate.Remaining == 0 {
+		if reset := resp.Rate.Reset.Time; reset.After(now) {
+			return reset.Sub(now) + jitter(minWait)
+		}
+	}
+
+	wait := maxWait
+	if attempt < 32 {
+		if d := minWait << attempt; d > 0 && d < maxWait {
+			wait = d
+		}
+	}
+
+	return wait/2 + jitter(wait/2)
+}
+
+// jitter returns a random duration in [0, d).
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+// observeRate remembers the rate limit DigitalOcean reported with resp.
+func (p *Provider) observeRate(resp *godo.Response) {
+	if resp == nil || resp.Rate.Limit == 0 {
+		return
+	}
+
+	p.rateMu.Lock()
+	defer p.rateMu.Unlock()
+	p.rate = resp.Rate
+}
+
+// throttleDelay returns how long to wait before the next request so that the
+// remaining quota is spread until the rate limit resets. No delay is needed
+// while more than RateLimitReserve requests remain.
+func (p *Provider) throttleDelay(now time.Time) time.Duration {
+	reserve := p.RateLimitReserve
+	if reserve == 0 {
+		reserve = defaultRateLimitReserve
+	}
+
+	p.rateMu.Lock()
+	rate := p.rate
+	p.rateMu.Unlock()
+
+	reset := rate.Reset.Time
+	if rate.Limit == 0 || rate.Remaining > reserve || !reset.After(now) {
+		return 0
+	}
+
+	return reset.Sub(now) / time.Duration(rate.Remaining+1)
+}
+
+// sleep waits for d, or until ctx is done. It fails right away if ctx would
+// expire before d has passed.
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return fmt.Errorf("waiting %s would exceed the context deadline: %w", d, context.DeadlineExceeded)
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
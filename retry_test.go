@@ -0,0 +1,168 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+	"github.com/wzzrd/libdns-digitalocean/digitaloceantest"
+)
+
+// responseWithStatus returns a godo.Response with the given status code and rate limit
+func responseWithStatus(status int, rate godo.Rate) *godo.Response {
+	return &godo.Response{Response: &http.Response{StatusCode: status}, Rate: rate}
+}
+
+func TestRetry_do(t *testing.T) {
+	tests := []struct {
+		name      string
+		statuses  []int
+		retries   int
+		create    bool
+		remove    bool
+		wantCalls int
+		wantErr   bool
+	}{
+		{name: "success", statuses: []int{200}, wantCalls: 1},
+		{name: "retry on 429", statuses: []int{429, 429, 200}, wantCalls: 3},
+		{name: "retry on 5xx", statuses: []int{500, 503, 200}, wantCalls: 3},
+		{name: "no retry on 4xx", statuses: []int{404}, wantCalls: 1, wantErr: true},
+		{name: "give up after MaxRetries", statuses: []int{500, 500, 500, 500}, retries: 2, wantCalls: 3, wantErr: true},
+		{name: "retries disabled", statuses: []int{500, 200}, retries: -1, wantCalls: 1, wantErr: true},
+		{name: "create retried on 429", statuses: []int{429, 201}, create: true, wantCalls: 2},
+		{name: "create not retried on 5xx", statuses: []int{502, 201}, create: true, wantCalls: 1, wantErr: true},
+		{name: "remove done before 5xx", statuses: []int{502, 404}, remove: true, wantCalls: 2},
+		{name: "remove not found", statuses: []int{404}, remove: true, wantCalls: 1, wantErr: true},
+		{name: "remove not found after 429", statuses: []int{429, 404}, remove: true, wantCalls: 2, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Provider{
+				MaxRetries:   tt.retries,
+				RetryMinWait: time.Millisecond,
+				RetryMaxWait: time.Millisecond,
+			}
+
+			calls := 0
+			err := p.do(context.Background(), &apiOp{method: "Test", create: tt.create, remove: tt.remove}, func() (*godo.Response, error) {
+				status := tt.statuses[calls]
+				calls++
+				if status >= 400 {
+					return responseWithStatus(status, godo.Rate{}), errors.New("API error")
+				}
+				return responseWithStatus(status, godo.Rate{}), nil
+			})
+
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Provider.do() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("Provider.do() made %d calls, want %d", calls, tt.wantCalls)
+			}
+		})
+	}
+}
+
+func TestRetry_createNotRepeated(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+	if _, err := server.AddDomain("example.com"); err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+
+	p := &Provider{APIToken: "test-token", BaseURL: server.URL, RetryMinWait: time.Millisecond, RetryMaxWait: time.Millisecond}
+
+	// The create may have been made before the gateway failed, so it is not sent again
+	server.Fail(http.StatusServiceUnavailable, 1)
+	_, err := p.AppendRecords(context.Background(), "example.com.", []libdns.Record{
+		libdns.TXT{Name: "test", Text: "hello", TTL: time.Hour},
+	})
+	if err == nil {
+		t.Error("Provider.AppendRecords() answered with 503 expected error, got nil")
+	}
+	if server.Requests() != 1 {
+		t.Errorf("Provider.AppendRecords() made %d requests, want 1", server.Requests())
+	}
+}
+
+func TestRetry_doDeadline(t *testing.T) {
+	p := &Provider{
+		RetryMinWait: time.Minute,
+		RetryMaxWait: time.Minute,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	apiErr := errors.New("API error")
+	start := time.Now()
+	err := p.do(ctx, &apiOp{method: "Test"}, func() (*godo.Response, error) {
+		return responseWithStatus(500, godo.Rate{}), apiErr
+	})
+
+	// The backoff does not fit in the deadline, so the call gives up right away
+	if !errors.Is(err, apiErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Provider.do() error = %v, want API error and deadline exceeded", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Provider.do() took %s, want it to give up right away", elapsed)
+	}
+}
+
+func TestRetry_backoff(t *testing.T) {
+	p := &Provider{
+		RetryMinWait: time.Second,
+		RetryMaxWait: 10 * time.Second,
+	}
+	now := time.Now()
+
+	// Exponential backoff, capped at RetryMaxWait
+	for attempt, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		got := p.backoff(attempt, responseWithStatus(500, godo.Rate{}), now)
+		if got < want/2 || got > want {
+			t.Errorf("Provider.backoff(%d) = %s, want between %s and %s", attempt, got, want/2, want)
+		}
+	}
+
+	// An exhausted rate limit waits for the reset
+	reset := now.Add(time.Minute)
+	rate := godo.Rate{Limit: 5000, Remaining: 0, Reset: godo.Timestamp{Time: reset}}
+	got := p.backoff(0, responseWithStatus(429, rate), now)
+	if got < time.Minute || got > time.Minute+time.Second {
+		t.Errorf("Provider.backoff() = %s, want to wait until the rate limit resets", got)
+	}
+}
+
+func TestRetry_throttleDelay(t *testing.T) {
+	p := &Provider{RateLimitReserve: 10}
+	now := time.Now()
+	reset := godo.Timestamp{Time: now.Add(time.Minute)}
+
+	// No rate limit seen yet
+	if got := p.throttleDelay(now); got != 0 {
+		t.Errorf("Provider.throttleDelay() = %s, want 0", got)
+	}
+
+	// Plenty of quota left
+	p.observeRate(responseWithStatus(200, godo.Rate{Limit: 5000, Remaining: 100, Reset: reset}))
+	if got := p.throttleDelay(now); got != 0 {
+		t.Errorf("Provider.throttleDelay() = %s, want 0", got)
+	}
+
+	// The remaining quota is spread until the reset
+	p.observeRate(responseWithStatus(200, godo.Rate{Limit: 5000, Remaining: 5, Reset: reset}))
+	if got := p.throttleDelay(now); got != 10*time.Second {
+		t.Errorf("Provider.throttleDelay() = %s, want 10s", got)
+	}
+
+	// Throttling can be disabled
+	p.RateLimitReserve = -1
+	if got := p.throttleDelay(now); got != 0 {
+		t.Errorf("Provider.throttleDelay() = %s, want 0", got)
+	}
+}
@@ -0,0 +1,148 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+)
+
+// Account is a DigitalOcean account, or team, and the zones it holds. Its
+// Provider carries the credentials and other settings used for those zones.
+type Account struct {
+	Provider
+	// Zones are the zones the account holds. A name such as "example.com"
+	// matches only that zone, while a name starting with a dot, such as
+	// ".example.com", matches all zones below it. "." matches every zone.
+	Zones []string `json:"zones"`
+}
+
+// Router implements the libdns interfaces for zones spread over several
+// DigitalOcean accounts, routing every call to the account that holds the
+// zone. When several accounts match a zone, the one with the longest
+// matching name wins. Each account creates its own client on first use.
+type Router struct {
+	Accounts []*Account `json:"accounts"`
+}
+
+// account returns the account that holds zone.
+func (r *Router) account(zone string) (*Account, error) {
+	fqdn := strings.ToLower(strings.TrimSuffix(zone, ".") + ".")
+
+	var best *Account
+	bestLen := -1
+	for _, account := range r.Accounts {
+		for _, name := range account.Zones {
+			name = strings.ToLower(name)
+			var matched bool
+			if strings.HasPrefix(name, ".") {
+				matched = strings.HasSuffix(fqdn, strings.TrimSuffix(name, ".")+".")
+			} else {
+				matched = fqdn == strings.TrimSuffix(name, ".")+"."
+			}
+			if matched && len(name) > bestLen {
+				best, bestLen = account, len(name)
+			}
+		}
+	}
+
+	if best == nil {
+		return nil, fmt.Errorf("digitalocean: no account holds zone %s", zone)
+	}
+	return best, nil
+}
+
+// GetRecords lists all the records in the zone, using the account that holds
+// it.
+func (r *Router) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
+	account, err := r.account(zone)
+	if err != nil {
+		return nil, err
+	}
+	return account.GetRecords(ctx, zone)
+}
+
+// AppendRecords adds records to the zone, using the account that holds it.
+func (r *Router) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	account, err := r.account(zone)
+	if err != nil {
+		return nil, err
+	}
+	return account.AppendRecords(ctx, zone, records)
+}
+
+// SetRecords sets the records in the zone, using the account that holds it.
+func (r *Router) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	account, err := r.account(zone)
+	if err != nil {
+		return nil, err
+	}
+	return account.SetRecords(ctx, zone, records)
+}
+
+// DeleteRecords deletes the records in the zone that match the input records,
+// using the account that holds it.
+func (r *Router) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	account, err := r.account(zone)
+	if err != nil {
+		return nil, err
+	}
+	return account.DeleteRecords(ctx, zone, records)
+}
+
+// ListZones lists the zones of all accounts. Only zones that are routed to
+// the account listing them are returned, so zones an account can see but
+// that are configured for another account are not listed twice.
+func (r *Router) ListZones(ctx context.Context) ([]libdns.Zone, error) {
+	var zones []libdns.Zone
+	for _, account := range r.Accounts {
+		accountZones, err := account.ListZones(ctx)
+		if err != nil {
+			return zones, err
+		}
+		for _, zone := range accountZones {
+			if routed, err := r.account(zone.Name); err == nil && routed == account {
+				zones = append(zones, zone)
+			}
+		}
+	}
+	return zones, nil
+}
+
+// CreateZone creates the zone in the account that holds it.
+func (r *Router) CreateZone(ctx context.Context, name string, opts ZoneOptions) (libdns.Zone, error) {
+	account, err := r.account(name)
+	if err != nil {
+		return libdns.Zone{}, err
+	}
+	return account.CreateZone(ctx, name, opts)
+}
+
+// DeleteZone deletes the zone from the account that holds it.
+func (r *Router) DeleteZone(ctx context.Context, name string) error {
+	account, err := r.account(name)
+	if err != nil {
+		return err
+	}
+	return account.DeleteZone(ctx, name)
+}
+
+// ForceDeleteZone deletes the zone and all its records from the account that
+// holds it.
+func (r *Router) ForceDeleteZone(ctx context.Context, name string) error {
+	account, err := r.account(name)
+	if err != nil {
+		return err
+	}
+	return account.ForceDeleteZone(ctx, name)
+}
+
+// Interface guards
+var (
+	_ libdns.RecordGetter   = (*Router)(nil)
+	_ libdns.RecordAppender = (*Router)(nil)
+	_ libdns.RecordSetter   = (*Router)(nil)
+	_ libdns.RecordDeleter  = (*Router)(nil)
+	_ libdns.ZoneLister     = (*Router)(nil)
+)
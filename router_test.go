@@ -0,0 +1,130 @@
+package digitalocean
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/libdns/libdns"
+	"github.com/wzzrd/libdns-digitalocean/digitaloceantest"
+)
+
+func TestRouter_account(t *testing.T) {
+	exact := &Account{Zones: []string{"example.com"}}
+	suffix := &Account{Zones: []string{".example.com", "example.net."}}
+	catchAll := &Account{Zones: []string{"."}}
+	r := &Router{Accounts: []*Account{catchAll, suffix, exact}}
+
+	tests := []struct {
+		zone string
+		want *Account
+	}{
+		{zone: "example.com.", want: exact},
+		{zone: "EXAMPLE.com", want: exact},
+		{zone: "sub.example.com.", want: suffix},
+		{zone: "example.net.", want: suffix},
+		{zone: "other.org.", want: catchAll},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.zone, func(t *testing.T) {
+			got, err := r.account(tt.zone)
+			if err != nil || got != tt.want {
+				t.Errorf("Router.account() = %v, %v, want %v", got, err, tt.want)
+			}
+		})
+	}
+
+	// Test error case
+	r = &Router{Accounts: []*Account{exact}}
+	if _, err := r.account("example.org."); err == nil {
+		t.Error("Router.account() expected error, got nil")
+	}
+}
+
+func TestRouter_records(t *testing.T) {
+	team1 := digitaloceantest.NewServer()
+	defer team1.Close()
+	team1.Token = "token-1"
+	team2 := digitaloceantest.NewServer()
+	defer team2.Close()
+	team2.Token = "token-2"
+
+	for _, server := range []*digitaloceantest.Server{team1, team2} {
+		if _, err := server.AddDomain("example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := team2.AddDomain("example.net"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Accounts are configured like any Provider
+	var r Router
+	config := `{"accounts": [
+		{"auth_token": "token-1", "base_url": "` + team1.URL + `", "zones": ["example.com"]},
+		{"auth_token": "token-2", "base_url": "` + team2.URL + `", "zones": ["example.net"]}
+	]}`
+	if err := json.Unmarshal([]byte(config), &r); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	ctx := context.Background()
+
+	for _, zone := range []string{"example.com.", "example.net."} {
+		if _, err := r.AppendRecords(ctx, zone, []libdns.Record{
+			libdns.RR{Type: "A", Name: "www", Data: "192.168.1.1"},
+		}); err != nil {
+			t.Fatalf("Router.AppendRecords(%s) error = %v", zone, err)
+		}
+	}
+
+	if got := len(team1.Records("example.com")); got != 5 {
+		t.Errorf("team 1 example.com has %d records, want 5", got)
+	}
+	if got := len(team2.Records("example.com")); got != 4 {
+		t.Errorf("team 2 example.com has %d records, want 4", got)
+	}
+	if got := len(team2.Records("example.net")); got != 5 {
+		t.Errorf("team 2 example.net has %d records, want 5", got)
+	}
+
+	// example.com is only listed for the account it is routed to
+	zones, err := r.ListZones(ctx)
+	if err != nil {
+		t.Fatalf("Router.ListZones() error = %v", err)
+	}
+	if len(zones) != 2 || zones[0].Name != "example.com." || zones[1].Name != "example.net." {
+		t.Errorf("Router.ListZones() = %v, want example.com. and example.net.", zones)
+	}
+
+	if _, err := r.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "A", Name: "www", Data: "192.168.1.2"},
+	}); err != nil {
+		t.Errorf("Router.SetRecords() error = %v", err)
+	}
+	if records, err := r.DeleteRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "A", Name: "www"},
+	}); err != nil || len(records) != 1 {
+		t.Errorf("Router.DeleteRecords() = %v, %v, want one record deleted", records, err)
+	}
+	if got := team1.Records("example.com"); len(got) != 4 || got[3].Type != "NS" {
+		t.Errorf("team 1 example.com holds %v, want only the SOA and NS records", got)
+	}
+
+	// example.net still holds the www record, so deleting it needs to be forced
+	if err := r.DeleteZone(ctx, "example.net."); !errors.Is(err, ErrZoneNotEmpty) {
+		t.Errorf("Router.DeleteZone() error = %v, want ErrZoneNotEmpty", err)
+	}
+	if err := r.ForceDeleteZone(ctx, "example.net."); err != nil {
+		t.Errorf("Router.ForceDeleteZone() error = %v", err)
+	}
+	if team2.Records("example.net") != nil || team2.Records("example.com") == nil {
+		t.Error("Router.ForceDeleteZone() did not delete only example.net from team 2")
+	}
+
+	// Test error case
+	if _, err := r.GetRecords(ctx, "example.org."); err == nil {
+		t.Error("Router.GetRecords() expected error, got nil")
+	}
+}
@@ -0,0 +1,167 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+const (
+	// defaultCommandRefresh is how long a token printed by a command is used
+	// before the command is run again, when no interval is given.
+	defaultCommandRefresh = 5 * time.Minute
+	// defaultCommandTimeout is how long a token command may run before it is
+	// killed, when no timeout is given.
+	defaultCommandTimeout = 30 * time.Second
+)
+
+// StaticToken returns a token source that always returns token.
+func StaticToken(token string) oauth2.TokenSource {
+	return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: cleanToken(token)})
+}
+
+// EnvToken returns a token source that reads the token from the environment
+// variable name every time it is asked for one.
+func EnvToken(name string) oauth2.TokenSource {
+	return envTokenSource(name)
+}
+
+type envTokenSource string
+
+func (name envTokenSource) Token() (*oauth2.Token, error) {
+	token := cleanToken(os.Getenv(string(name)))
+	if token == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", string(name))
+	}
+	return &oauth2.Token{AccessToken: token}, nil
+}
+
+// FileToken returns a token source that reads the token from the file at
+// path. The file is read again whenever its size or modification time
+// changes, so a rotated token is picked up by the next request.
+func FileToken(path string) oauth2.TokenSource {
+	return &fileTokenSource{path: path}
+}
+
+type fileTokenSource struct {
+	path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+	size    int64
+}
+
+func (s *fileTokenSource) Token() (*oauth2.Token, error) {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token == "" || !info.ModTime().Equal(s.modTime) || info.Size() != s.size {
+		data, err := os.ReadFile(s.path)
+		if err != nil {
+			return nil, err
+		}
+		token := cleanToken(string(data))
+		if token == "" {
+			return nil, fmt.Errorf("token file %s is empty", s.path)
+		}
+		s.token, s.modTime, s.size = token, info.ModTime(), info.Size()
+	}
+
+	return &oauth2.Token{AccessToken: s.token}, nil
+}
+
+// CommandToken returns a token source that runs a command and uses what it
+// prints to standard output as the token, for example to fetch the token from
+// a secret manager. The command is run again once refresh has passed, or
+// after five minutes if refresh is zero. A command that does not finish
+// within 30 seconds is killed, as API calls wait for the token.
+func CommandToken(refresh time.Duration, name string, args ...string) oauth2.TokenSource {
+	return commandToken(refresh, 0, name, args...)
+}
+
+// commandToken is CommandToken with a timeout for the command, 30 seconds if
+// timeout is zero.
+func commandToken(refresh, timeout time.Duration, name string, args ...string) oauth2.TokenSource {
+	if refresh <= 0 {
+		refresh = defaultCommandRefresh
+	}
+	if timeout <= 0 {
+		timeout = defaultCommandTimeout
+	}
+	return oauth2.ReuseTokenSource(nil, &commandTokenSource{refresh: refresh, timeout: timeout, name: name, args: args})
+}
+
+type commandTokenSource struct {
+	refresh time.Duration
+	timeout time.Duration
+	name    string
+	args    []string
+}
+
+func (s *commandTokenSource) Token() (*oauth2.Token, error) {
+	// oauth2 asks for tokens without a context, so the timeout bounds the wait
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.name, s.args...)
+	cmd.Stderr = &stderr
+	// do not wait for children that keep the output open after the command is killed
+	cmd.WaitDelay = time.Second
+
+	out, err := cmd.Output()
+	if ctx.Err() != nil {
+		return nil, fmt.Errorf("token command %s did not finish within %s", s.name, s.timeout)
+	}
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return nil, fmt.Errorf("token command %s: %w: %s", s.name, err, msg)
+		}
+		return nil, fmt.Errorf("token command %s: %w", s.name, err)
+	}
+
+	token := cleanToken(string(out))
+	if token == "" {
+		return nil, fmt.Errorf("token command %s printed no token", s.name)
+	}
+	return &oauth2.Token{AccessToken: token, Expiry: time.Now().Add(s.refresh)}, nil
+}
+
+// tokenSource returns the source of the API token configured on the Provider.
+// TokenSource takes precedence over TokenCommand, TokenFile, TokenEnv and
+// APIToken, in that order.
+func (p *Provider) tokenSource() (oauth2.TokenSource, error) {
+	switch {
+	case p.TokenSource != nil:
+		return p.TokenSource, nil
+	case len(p.TokenCommand) > 0:
+		return commandToken(p.TokenCommandRefresh, p.TokenCommandTimeout, p.TokenCommand[0], p.TokenCommand[1:]...), nil
+	case p.TokenFile != "":
+		return FileToken(p.TokenFile), nil
+	case p.TokenEnv != "":
+		return EnvToken(p.TokenEnv), nil
+	case cleanToken(p.APIToken) != "":
+		return StaticToken(p.APIToken), nil
+	default:
+		return nil, errors.New("digitalocean: API token is not set")
+	}
+}
+
+// cleanToken strips the whitespace and quotes a token is often copied with.
+func cleanToken(token string) string {
+	return strings.Trim(strings.TrimSpace(token), `'"`)
+}
@@ -0,0 +1,134 @@
+package digitalocean
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// tokenOf returns the token ts returns, failing the test on error
+func tokenOf(t *testing.T, ts oauth2.TokenSource) string {
+	t.Helper()
+	token, err := ts.Token()
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	return token.AccessToken
+}
+
+func TestToken_EnvToken(t *testing.T) {
+	t.Setenv("DO_TEST_TOKEN", " first\n")
+	ts := EnvToken("DO_TEST_TOKEN")
+
+	if got := tokenOf(t, ts); got != "first" {
+		t.Errorf("EnvToken() = %q, want first", got)
+	}
+
+	t.Setenv("DO_TEST_TOKEN", "second")
+	if got := tokenOf(t, ts); got != "second" {
+		t.Errorf("EnvToken() = %q, want second", got)
+	}
+
+	// Test error case
+	t.Setenv("DO_TEST_TOKEN", "")
+	if _, err := ts.Token(); err == nil {
+		t.Error("EnvToken() expected error, got nil")
+	}
+}
+
+func TestToken_FileToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	ts := FileToken(path)
+
+	if got := tokenOf(t, ts); got != "first" {
+		t.Errorf("FileToken() = %q, want first", got)
+	}
+
+	// A rotated token is picked up
+	if err := os.WriteFile(path, []byte("rotated\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if got := tokenOf(t, ts); got != "rotated" {
+		t.Errorf("FileToken() = %q, want rotated", got)
+	}
+
+	// Test error case
+	if _, err := FileToken(filepath.Join(t.TempDir(), "missing")).Token(); err == nil {
+		t.Error("FileToken() expected error, got nil")
+	}
+}
+
+func TestToken_CommandToken(t *testing.T) {
+	if _, err := exec.LookPath("echo"); err != nil {
+		t.Skip("echo is not available")
+	}
+
+	if got := tokenOf(t, CommandToken(time.Minute, "echo", "from-command")); got != "from-command" {
+		t.Errorf("CommandToken() = %q, want from-command", got)
+	}
+
+	// Test error cases
+	if _, err := CommandToken(0, "false").Token(); err == nil {
+		t.Error("CommandToken() expected error, got nil")
+	}
+
+	if _, err := exec.LookPath("sleep"); err == nil {
+		start := time.Now()
+		if _, err := commandToken(0, 50*time.Millisecond, "sleep", "10").Token(); err == nil {
+			t.Error("commandToken() of a hanging command expected error, got nil")
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("commandToken() of a hanging command took %s, want it killed after the timeout", elapsed)
+		}
+	}
+}
+
+func TestToken_cleanToken(t *testing.T) {
+	for _, token := range []string{"secret", " secret\n", "'secret'", `"secret"`} {
+		if got := cleanToken(token); got != "secret" {
+			t.Errorf("cleanToken(%q) = %q, want secret", token, got)
+		}
+	}
+}
+
+func TestToken_rotation(t *testing.T) {
+	var gotAuth []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = append(gotAuth, r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"domains": []}`))
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &Provider{APIToken: "ignored", TokenFile: path, BaseURL: server.URL}
+	ctx := context.Background()
+
+	if _, err := p.ListZones(ctx); err != nil {
+		t.Fatalf("Provider.ListZones() error = %v", err)
+	}
+	if err := os.WriteFile(path, []byte("second-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.ListZones(ctx); err != nil {
+		t.Fatalf("Provider.ListZones() error = %v", err)
+	}
+
+	if len(gotAuth) != 2 || gotAuth[0] != "Bearer first" || gotAuth[1] != "Bearer second-token" {
+		t.Errorf("Authorization headers = %v, want the rotated token on the second request", gotAuth)
+	}
+}
@@ -0,0 +1,91 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/libdns/libdns"
+)
+
+// RollbackError is returned in transactional mode when a batch operation
+// failed and the changes it already made were rolled back. Err is the original
+// failure, usually a *BatchError. RollbackErr is nil if the zone was restored
+// to its previous state, and describes the inverse operations that failed
+// otherwise.
+type RollbackError struct {
+	Err         error
+	RollbackErr error
+}
+
+func (e *RollbackError) Error() string {
+	if e.RollbackErr != nil {
+		return fmt.Sprintf("%v; rollback failed: %v", e.Err, e.RollbackErr)
+	}
+	return fmt.Sprintf("%v; all changes were rolled back", e.Err)
+}
+
+func (e *RollbackError) Unwrap() []error {
+	if e.RollbackErr != nil {
+		return []error{e.Err, e.RollbackErr}
+	}
+	return []error{e.Err}
+}
+
+// RolledBack reports whether the zone was restored to its previous state.
+func (e *RollbackError) RolledBack() bool {
+	return e.RollbackErr == nil
+}
+
+// journal keeps track of the changes made by a batch operation, so they can be
+// undone if the operation fails.
+type journal struct {
+	// snapshot holds the records as they were before the batch, by ID
+	snapshot map[int]libdns.Record
+
+	created []libdns.Record
+	updated []libdns.Record
+	deleted []libdns.Record
+}
+
+// snapshotOf indexes records read from DigitalOcean by their ID.
+func snapshotOf(records []libdns.Record) map[int]libdns.Record {
+	snapshot := make(map[int]libdns.Record, len(records))
+	for _, record := range records {
+		if id, err := idFromRecord(record); err == nil {
+			snapshot[id] = record
+		}
+	}
+	return snapshot
+}
+
+// rollback undoes the changes in j, in the reverse order of how a batch makes
+// them: deleted records are recreated, edited records are restored from the
+// snapshot and created records are deleted. Recreated records get new IDs. The
+// returned *RollbackError wraps cause.
+func (p *Provider) rollback(ctx context.Context, zone string, cause error, j journal) error {
+	// the batch may have failed because ctx was cancelled, which must not
+	// stop the rollback
+	ctx = context.WithoutCancel(ctx)
+
+	var restored []libdns.Record
+	for _, record := range j.updated {
+		if id, err := idFromRecord(record); err == nil {
+			if previous, ok := j.snapshot[id]; ok {
+				restored = append(restored, previous)
+			}
+		}
+	}
+
+	recreated, failed := p.applyEach(ctx, zone, j.deleted, p.addDNSEntry)
+	reverted, restoreFailed := p.applyEach(ctx, zone, restored, p.updateDNSEntry)
+	removed, removeFailed := p.applyEach(ctx, zone, j.created, p.removeDNSEntry)
+	failed = append(append(failed, restoreFailed...), removeFailed...)
+
+	rollbackErr := &RollbackError{Err: cause}
+	if len(failed) > 0 {
+		succeeded := append(append(recreated, reverted...), removed...)
+		rollbackErr.RollbackErr = &BatchError{Succeeded: succeeded, Failed: failed}
+	}
+
+	return rollbackErr
+}
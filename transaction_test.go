@@ -0,0 +1,117 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+)
+
+func TestTransaction_AppendRecords(t *testing.T) {
+	p := setupTest(nil, nil)
+	p.Transactional = true
+	mock := p.client.Domains.(*mockDomainsService)
+	mock.failData = "192.168.1.2"
+	ctx := context.Background()
+
+	appendedRecords, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "A", Name: "one", Data: "192.168.1.1"},
+		libdns.RR{Type: "A", Name: "two", Data: "192.168.1.2"},
+		libdns.RR{Type: "A", Name: "three", Data: "192.168.1.3"},
+	})
+
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) || !rollbackErr.RolledBack() {
+		t.Fatalf("Provider.AppendRecords() error = %v, want rolled back *RollbackError", err)
+	}
+
+	var batchErr *BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Failed) != 1 {
+		t.Errorf("Provider.AppendRecords() error = %v, want the original *BatchError", err)
+	}
+
+	if appendedRecords != nil {
+		t.Errorf("Provider.AppendRecords() = %v, want nil", appendedRecords)
+	}
+
+	// Both records that were created are deleted again
+	if len(mock.deleted) != 2 || mock.deleted[0] != 12345 || mock.deleted[1] != 12345 {
+		t.Errorf("Provider.AppendRecords() deleted = %v, want [12345 12345]", mock.deleted)
+	}
+}
+
+func TestTransaction_SetRecords(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 1, Type: "A", Name: "one", Data: "192.168.1.1", TTL: 3600},
+		{ID: 2, Type: "A", Name: "one", Data: "192.168.1.2", TTL: 3600},
+		{ID: 3, Type: "A", Name: "two", Data: "192.168.1.3", TTL: 3600},
+	}
+
+	p := setupTest(mockRecords, nil)
+	p.Transactional = true
+	mock := p.client.Domains.(*mockDomainsService)
+	mock.failData = "192.168.1.30"
+	ctx := context.Background()
+
+	setRecords, err := p.SetRecords(ctx, "example.com.", []libdns.Record{
+		libdns.RR{Type: "A", Name: "one", Data: "192.168.1.10", TTL: 3600 * time.Second},
+		libdns.RR{Type: "A", Name: "two", Data: "192.168.1.30", TTL: 3600 * time.Second},
+	})
+
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) || !rollbackErr.RolledBack() {
+		t.Fatalf("Provider.SetRecords() error = %v, want rolled back *RollbackError", err)
+	}
+	if setRecords != nil {
+		t.Errorf("Provider.SetRecords() = %v, want nil", setRecords)
+	}
+
+	// Record 1 is edited and then restored, nothing is deleted
+	if len(mock.edited) != 2 || mock.edited[0] != 1 || mock.edited[1] != 1 {
+		t.Errorf("Provider.SetRecords() edited = %v, want [1 1]", mock.edited)
+	}
+	if len(mock.deleted) != 0 {
+		t.Errorf("Provider.SetRecords() deleted = %v, want none", mock.deleted)
+	}
+}
+
+func TestTransaction_rollback(t *testing.T) {
+	snapshot := []libdns.Record{
+		fromGodo("example.com", godo.DomainRecord{ID: 1, Type: "A", Name: "one", Data: "192.168.1.1", TTL: 3600}),
+		fromGodo("example.com", godo.DomainRecord{ID: 2, Type: "A", Name: "two", Data: "192.168.1.2", TTL: 3600}),
+	}
+
+	p := setupTest(nil, nil)
+	mock := p.client.Domains.(*mockDomainsService)
+	ctx := context.Background()
+
+	cause := errors.New("batch failed")
+	err := p.rollback(ctx, "example.com", cause, journal{
+		snapshot: snapshotOf(snapshot),
+		created:  []libdns.Record{withID(libdns.RR{Type: "A", Name: "three", Data: "192.168.1.3"}, 3)},
+		updated:  []libdns.Record{withID(libdns.RR{Type: "A", Name: "one", Data: "192.168.1.10"}, 1)},
+		deleted:  []libdns.Record{snapshot[1]},
+	})
+
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) || !rollbackErr.RolledBack() || !errors.Is(err, cause) {
+		t.Fatalf("Provider.rollback() error = %v, want rolled back *RollbackError", err)
+	}
+	if len(mock.edited) != 1 || mock.edited[0] != 1 {
+		t.Errorf("Provider.rollback() edited = %v, want [1]", mock.edited)
+	}
+	if len(mock.deleted) != 1 || mock.deleted[0] != 3 {
+		t.Errorf("Provider.rollback() deleted = %v, want [3]", mock.deleted)
+	}
+
+	// Test error case
+	p = setupTest(nil, errors.New("API error"))
+
+	err = p.rollback(ctx, "example.com", cause, journal{created: []libdns.Record{snapshot[0]}})
+	if !errors.As(err, &rollbackErr) || rollbackErr.RolledBack() {
+		t.Errorf("Provider.rollback() error = %v, want failed rollback", err)
+	}
+}
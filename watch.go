@@ -0,0 +1,148 @@
+package digitalocean
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+const (
+	// defaultWatchInterval is how often Watch polls a zone when no interval is
+	// given.
+	defaultWatchInterval = time.Minute
+	// maxWatchBackoff bounds how far Watch stretches its interval after failed
+	// polls.
+	maxWatchBackoff = 15 * time.Minute
+)
+
+// EventType is the kind of change an Event reports.
+type EventType int
+
+const (
+	// EventAdded reports a record that was created.
+	EventAdded EventType = iota + 1
+	// EventModified reports a record whose name, type, TTL or data changed.
+	EventModified
+	// EventRemoved reports a record that was deleted.
+	EventRemoved
+)
+
+func (t EventType) String() string {
+	switch t {
+	case EventAdded:
+		return "added"
+	case EventModified:
+		return "modified"
+	case EventRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a change to a record of a watched zone.
+type Event struct {
+	Type EventType
+	// Zone is the zone of the record, without a trailing dot
+	Zone string
+	// ID is the DigitalOcean ID of the record
+	ID int
+	// Old is the record before the change, nil for EventAdded
+	Old libdns.Record
+	// New is the record after the change, nil for EventRemoved
+	New libdns.Record
+}
+
+// Watch polls the records of the zone every interval, or every minute if
+// interval is zero, and calls fn for every record that was added, modified or
+// removed since the previous poll, for example to react to changes made in the
+// DigitalOcean control panel. Changes made through the Provider are reported
+// too. Records are told apart by their ID, so a record that is deleted and
+// created again is reported as removed and added.
+//
+// Watch blocks until ctx is done and then returns ctx.Err(). Polls that fail,
+// for example because the rate limit is exhausted, are retried with the
+// interval doubled each time, up to 15 minutes. Watch returns an error if the
+// zone cannot be read at all, or when a poll is rejected for a reason retrying
+// does not fix, such as an invalid token or a deleted zone.
+func (p *Provider) Watch(ctx context.Context, zone string, interval time.Duration, fn func(Event)) error {
+	zone = p.unFQDN(zone)
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+
+	// polls bypass the cache, as changes made elsewhere do not show up in it
+	ctx = WithoutCache(ctx)
+
+	previous, err := p.getDNSEntries(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	wait := interval
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		current, err := p.getDNSEntries(ctx, zone)
+		switch {
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil && permanent(err):
+			return err
+		case err != nil:
+			wait = min(2*wait, max(maxWatchBackoff, interval))
+		default:
+			for _, event := range diffSnapshots(zone, previous, current) {
+				fn(event)
+			}
+			previous, wait = current, interval
+		}
+
+		timer.Reset(wait)
+	}
+}
+
+// diffSnapshots returns the events that turn the records in previous into the
+// ones in current: removals first, then modifications, then additions, each
+// ordered by record ID.
+func diffSnapshots(zone string, previous, current []libdns.Record) []Event {
+	before, after := snapshotOf(previous), snapshotOf(current)
+
+	var removed, modified, added []Event
+	for id, old := range before {
+		record, ok := after[id]
+		switch {
+		case !ok:
+			removed = append(removed, Event{Type: EventRemoved, Zone: zone, ID: id, Old: old})
+		case record.RR() != old.RR():
+			modified = append(modified, Event{Type: EventModified, Zone: zone, ID: id, Old: old, New: record})
+		}
+	}
+	for id, record := range after {
+		if _, ok := before[id]; !ok {
+			added = append(added, Event{Type: EventAdded, Zone: zone, ID: id, New: record})
+		}
+	}
+
+	for _, events := range [][]Event{removed, modified, added} {
+		sort.Slice(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+	}
+	return append(append(removed, modified...), added...)
+}
+
+// permanent reports whether err is an API error that retrying the same
+// request does not fix, that is a 4xx status other than 429.
+func permanent(err error) bool {
+	status := errorStatus(err)
+	return status >= 400 && status < 500 && status != http.StatusTooManyRequests
+}
@@ -0,0 +1,130 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+	"github.com/wzzrd/libdns-digitalocean/digitaloceantest"
+)
+
+func TestWatch_diffSnapshots(t *testing.T) {
+	previous := []libdns.Record{
+		withID(libdns.RR{Name: "www", Type: "A", Data: "192.168.1.1", TTL: time.Hour}, 1),
+		withID(libdns.RR{Name: "mail", Type: "A", Data: "192.168.1.2", TTL: time.Hour}, 2),
+		withID(libdns.RR{Name: "old", Type: "TXT", Data: "gone", TTL: time.Hour}, 3),
+	}
+	current := []libdns.Record{
+		withID(libdns.RR{Name: "www", Type: "A", Data: "192.168.1.1", TTL: time.Hour}, 1),
+		withID(libdns.RR{Name: "mail", Type: "A", Data: "192.168.1.2", TTL: time.Minute}, 2),
+		withID(libdns.RR{Name: "new", Type: "TXT", Data: "here", TTL: time.Hour}, 5),
+		withID(libdns.RR{Name: "newer", Type: "TXT", Data: "here", TTL: time.Hour}, 4),
+	}
+
+	var got []string
+	for _, event := range diffSnapshots("example.com", previous, current) {
+		got = append(got, fmt.Sprintf("%s %d", event.Type, event.ID))
+		if event.Zone != "example.com" {
+			t.Errorf("event %s %d has zone %q, want example.com", event.Type, event.ID, event.Zone)
+		}
+		if (event.Old == nil) != (event.Type == EventAdded) || (event.New == nil) != (event.Type == EventRemoved) {
+			t.Errorf("event %s %d has Old %v and New %v", event.Type, event.ID, event.Old, event.New)
+		}
+	}
+	if want := "[removed 3 modified 2 added 4 added 5]"; fmt.Sprint(got) != want {
+		t.Errorf("diffSnapshots() = %v, want %s", got, want)
+	}
+
+	if events := diffSnapshots("example.com", current, current); len(events) != 0 {
+		t.Errorf("diffSnapshots() of unchanged records = %v, want none", events)
+	}
+}
+
+func TestWatch_Watch(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+	server.Token = "test-token"
+
+	added, err := server.AddDomain("example.com", godo.DomainRecord{Type: "A", Name: "www", Data: "192.168.1.1", TTL: 3600})
+	if err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+
+	p := &Provider{APIToken: "test-token", BaseURL: server.URL, MaxRetries: -1}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events := make(chan Event, 10)
+	done := make(chan error, 1)
+	go func() {
+		done <- p.Watch(ctx, "example.com.", 5*time.Millisecond, func(event Event) { events <- event })
+	}()
+
+	next := func() Event {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case err := <-done:
+			t.Fatalf("Provider.Watch() returned early: %v", err)
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+		}
+		return Event{}
+	}
+
+	// Wait for the first poll, which is only sent once the initial snapshot was
+	// served, before changing the zone behind the Provider's back
+	for server.Requests() < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	client := server.Client()
+	if _, _, err := client.Domains.EditRecord(ctx, "example.com", added[0].ID, &godo.DomainRecordEditRequest{Type: "A", Name: "www", Data: "192.168.1.2", TTL: 3600}); err != nil {
+		t.Fatalf("EditRecord() error = %v", err)
+	}
+	if event := next(); event.Type != EventModified || event.ID != added[0].ID || event.New.RR().Data != "192.168.1.2" || event.Old.RR().Data != "192.168.1.1" {
+		t.Errorf("Provider.Watch() reported %+v, want the A record modified", event)
+	}
+
+	// Failed polls are retried
+	before := server.Requests()
+	server.Fail(http.StatusTooManyRequests, 2)
+	for server.Requests() < before+2 {
+		time.Sleep(time.Millisecond)
+	}
+	rec, _, err := client.Domains.CreateRecord(ctx, "example.com", &godo.DomainRecordEditRequest{Type: "TXT", Name: "test", Data: "hello", TTL: 60})
+	if err != nil {
+		t.Fatalf("CreateRecord() error = %v", err)
+	}
+	if event := next(); event.Type != EventAdded || event.ID != rec.ID || event.New.RR().Type != "TXT" {
+		t.Errorf("Provider.Watch() reported %+v, want the TXT record added", event)
+	}
+
+	if _, err := client.Domains.DeleteRecord(ctx, "example.com", rec.ID); err != nil {
+		t.Fatalf("DeleteRecord() error = %v", err)
+	}
+	if event := next(); event.Type != EventRemoved || event.ID != rec.ID {
+		t.Errorf("Provider.Watch() reported %+v, want the TXT record removed", event)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Provider.Watch() error = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Provider.Watch() did not stop after cancel")
+	}
+
+	// Test error case
+	err = p.Watch(context.Background(), "missing.com.", time.Millisecond, func(Event) {})
+	if err == nil {
+		t.Error("Provider.Watch() of a missing zone expected error, got nil")
+	}
+}
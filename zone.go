@@ -0,0 +1,138 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+)
+
+// ErrZoneNotEmpty is returned by DeleteZone for a zone that still holds
+// records. ForceDeleteZone deletes such zones.
+var ErrZoneNotEmpty = errors.New("zone is not empty")
+
+// ZoneOptions are the settings of a zone created by CreateZone.
+type ZoneOptions struct {
+	// IPAddress, if set, is the address of an A record, or AAAA record for an
+	// IPv6 address, created at the apex of the new zone.
+	IPAddress string
+}
+
+// CreateZone adds the zone (domain) to the DigitalOcean account, along with
+// DigitalOcean's SOA and NS records. Creating a zone that already exists in the
+// account is not an error: the zone is returned as it is, and opts are not
+// applied to it.
+func (p *Provider) CreateZone(ctx context.Context, name string, opts ZoneOptions) (libdns.Zone, error) {
+	if err := p.getClient(); err != nil {
+		return libdns.Zone{}, err
+	}
+	name = p.unFQDN(name)
+	zone := libdns.Zone{Name: name + "."}
+
+	if dryRun(ctx) != nil {
+		return zone, errors.New("digitalocean: zones cannot be created in a dry run")
+	}
+
+	exists, err := p.zoneExists(ctx, name)
+	if err != nil || exists {
+		return zone, err
+	}
+
+	op := &apiOp{method: "Domains.Create", zone: name, data: opts.IPAddress, create: true}
+	err = p.do(ctx, op, func() (*godo.Response, error) {
+		_, resp, err := p.client.Domains.Create(ctx, &godo.DomainCreateRequest{Name: name, IPAddress: opts.IPAddress})
+		return resp, err
+	})
+	if err != nil && errorStatus(err) == http.StatusUnprocessableEntity {
+		// the zone may have been created since it was looked up
+		if exists, existsErr := p.zoneExists(ctx, name); existsErr == nil && exists {
+			return zone, nil
+		}
+	}
+	if err != nil {
+		return zone, err
+	}
+	p.InvalidateZone(name)
+
+	return zone, nil
+}
+
+// DeleteZone removes the zone (domain) from the DigitalOcean account. To guard
+// against mistakes, a zone holding records other than DigitalOcean's SOA and
+// NS records is not deleted; an error wrapping ErrZoneNotEmpty is returned
+// instead. Deleting a zone that does not exist is not an error.
+func (p *Provider) DeleteZone(ctx context.Context, name string) error {
+	return p.deleteZone(ctx, name, false)
+}
+
+// ForceDeleteZone removes the zone (domain) and all its records from the
+// DigitalOcean account, whether or not it holds records. Deleting a zone that
+// does not exist is not an error.
+func (p *Provider) ForceDeleteZone(ctx context.Context, name string) error {
+	return p.deleteZone(ctx, name, true)
+}
+
+// deleteZone deletes the zone, if it holds no records of its own or force is
+// set.
+func (p *Provider) deleteZone(ctx context.Context, name string, force bool) error {
+	if err := p.getClient(); err != nil {
+		return err
+	}
+	name = p.unFQDN(name)
+
+	if dryRun(ctx) != nil {
+		return errors.New("digitalocean: zones cannot be deleted in a dry run")
+	}
+
+	unlock := p.lockZone(name)
+	defer unlock()
+
+	if !force {
+		records, err := p.getDNSEntries(WithoutCache(ctx), name)
+		if errorStatus(err) == http.StatusNotFound {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		content := 0
+		for _, record := range records {
+			if !managedByDigitalOcean(record) {
+				content++
+			}
+		}
+		if content > 0 {
+			return fmt.Errorf("digitalocean: zone %s holds %d records: %w", name, content, ErrZoneNotEmpty)
+		}
+	}
+
+	err := p.do(ctx, &apiOp{method: "Domains.Delete", zone: name}, func() (*godo.Response, error) {
+		return p.client.Domains.Delete(ctx, name)
+	})
+	p.InvalidateZone(name)
+	if errorStatus(err) == http.StatusNotFound {
+		return nil
+	}
+
+	return err
+}
+
+// zoneExists reports whether the zone is in the DigitalOcean account.
+func (p *Provider) zoneExists(ctx context.Context, name string) (bool, error) {
+	err := p.do(ctx, &apiOp{method: "Domains.Get", zone: name}, func() (*godo.Response, error) {
+		_, resp, err := p.client.Domains.Get(ctx, name)
+		return resp, err
+	})
+	switch {
+	case err == nil:
+		return true, nil
+	case errorStatus(err) == http.StatusNotFound:
+		return false, nil
+	default:
+		return false, err
+	}
+}
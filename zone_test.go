@@ -0,0 +1,106 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/libdns/libdns"
+	"github.com/wzzrd/libdns-digitalocean/digitaloceantest"
+)
+
+func TestZone_CreateZone(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+
+	p := &Provider{APIToken: "test-token", BaseURL: server.URL}
+	ctx := context.Background()
+
+	zone, err := p.CreateZone(ctx, "example.com.", ZoneOptions{IPAddress: "192.168.1.1"})
+	if err != nil {
+		t.Fatalf("Provider.CreateZone() error = %v", err)
+	}
+	if zone.Name != "example.com." {
+		t.Errorf("Provider.CreateZone() = %v, want example.com.", zone)
+	}
+
+	records, err := p.GetRecordsByType(ctx, "example.com.", "A")
+	if err != nil {
+		t.Fatalf("Provider.GetRecordsByType() error = %v", err)
+	}
+	if len(records) != 1 || records[0].RR().Name != "@" || records[0].RR().Data != "192.168.1.1" {
+		t.Errorf("new zone holds A records %v, want one at the apex", records)
+	}
+
+	// Creating the zone again leaves it as it is
+	if _, err := p.CreateZone(ctx, "example.com", ZoneOptions{IPAddress: "192.168.1.2"}); err != nil {
+		t.Errorf("Provider.CreateZone() of an existing zone error = %v, want nil", err)
+	}
+	if got := len(server.Records("example.com")); got != 5 {
+		t.Errorf("zone holds %d records after creating it again, want 5", got)
+	}
+
+	// Test error cases
+	if _, err := p.CreateZone(ctx, "invalid", ZoneOptions{}); err == nil {
+		t.Error("Provider.CreateZone() with an invalid name expected error, got nil")
+	}
+	if _, err := p.CreateZone(WithDryRun(ctx, &ChangeSet{}), "example.org", ZoneOptions{}); err == nil {
+		t.Error("Provider.CreateZone() in a dry run expected error, got nil")
+	}
+}
+
+func TestZone_DeleteZone(t *testing.T) {
+	server := digitaloceantest.NewServer()
+	defer server.Close()
+
+	p := &Provider{APIToken: "test-token", BaseURL: server.URL, CacheTTL: time.Minute}
+	ctx := context.Background()
+
+	// A zone with only DigitalOcean's records is deleted
+	if _, err := server.AddDomain("empty.com"); err != nil {
+		t.Fatalf("Server.AddDomain() error = %v", err)
+	}
+	if err := p.DeleteZone(ctx, "empty.com."); err != nil {
+		t.Errorf("Provider.DeleteZone() of an empty zone error = %v", err)
+	}
+	if server.Records("empty.com") != nil {
+		t.Error("Provider.DeleteZone() left empty.com in place")
+	}
+
+	// A zone with records needs to be forced
+	if _, err := p.CreateZone(ctx, "example.com.", ZoneOptions{}); err != nil {
+		t.Fatalf("Provider.CreateZone() error = %v", err)
+	}
+	if _, err := p.AppendRecords(ctx, "example.com.", []libdns.Record{
+		libdns.TXT{Name: "test", Text: "hello", TTL: time.Hour},
+	}); err != nil {
+		t.Fatalf("Provider.AppendRecords() error = %v", err)
+	}
+	if err := p.DeleteZone(ctx, "example.com."); !errors.Is(err, ErrZoneNotEmpty) {
+		t.Errorf("Provider.DeleteZone() of a zone with records error = %v, want ErrZoneNotEmpty", err)
+	}
+	if server.Records("example.com") == nil {
+		t.Fatal("Provider.DeleteZone() deleted a zone with records")
+	}
+
+	if err := p.ForceDeleteZone(ctx, "example.com."); err != nil {
+		t.Errorf("Provider.ForceDeleteZone() error = %v", err)
+	}
+	if server.Records("example.com") != nil {
+		t.Error("Provider.ForceDeleteZone() left example.com in place")
+	}
+
+	// The cached records of the zone are dropped
+	if _, err := p.GetRecords(ctx, "example.com."); err == nil {
+		t.Error("Provider.GetRecords() of a deleted zone expected error, got nil")
+	}
+
+	// Deleting a missing zone is not an error
+	if err := p.DeleteZone(ctx, "example.com."); err != nil {
+		t.Errorf("Provider.DeleteZone() of a missing zone error = %v", err)
+	}
+	if err := p.ForceDeleteZone(ctx, "example.com."); err != nil {
+		t.Errorf("Provider.ForceDeleteZone() of a missing zone error = %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package digitalocean
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// maxTXTString is the longest character-string a TXT record can hold; longer
+// values are split into several strings.
+const maxTXTString = 255
+
+// Fields of the SOA record written by WriteZoneFile, the values DigitalOcean
+// serves.
+const (
+	soaNameServer = "ns1.digitalocean.com."
+	soaSerial     = 1
+	soaRefresh    = 10800
+	soaRetry      = 3600
+	soaExpire     = 604800
+)
+
+// ExportZone writes the records of the zone to w as an RFC 1035 master file.
+// See WriteZoneFile.
+func (p *Provider) ExportZone(ctx context.Context, zone string, w io.Writer) error {
+	records, err := p.GetRecords(ctx, zone)
+	if err != nil {
+		return err
+	}
+
+	return WriteZoneFile(w, zone, records)
+}
+
+// WriteZoneFile writes records, as returned by GetRecords, to w as an RFC 1035
+// master file for zone. The file starts with $ORIGIN and $TTL directives, owner
+// names are written relative to the origin and targets fully qualified. TXT
+// values are quoted and split into strings of at most 255 bytes.
+//
+// The SOA record DigitalOcean returns does not hold a complete SOA, so an SOA
+// is written in its place, as DNS servers do not load a zone without one. It
+// names the first NS record at the apex as the primary server, and uses the
+// $TTL value as its TTL and minimum; the other fields are fixed.
+func WriteZoneFile(w io.Writer, zone string, records []libdns.Record) error {
+	origin := strings.TrimSuffix(zone, ".") + "."
+	ttl := int(commonTTL(records).Seconds())
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "$ORIGIN %s\n", origin)
+	fmt.Fprintf(bw, "$TTL %d\n", ttl)
+	fmt.Fprintf(bw, "@\t%d\tIN\tSOA\t%s hostmaster.%s %d %d %d %d %d\n",
+		ttl, primaryNameServer(zone, records), origin, soaSerial, soaRefresh, soaRetry, soaExpire, ttl)
+
+	for _, record := range records {
+		rr := record.RR()
+		if rr.Type == "SOA" {
+			continue
+		}
+
+		data, err := zoneFileData(zone, rr)
+		if err != nil {
+			return RecordError{Record: record, Err: err}
+		}
+
+		name := relativeName(rr.Name, zone)
+		if name == "" {
+			name = "@"
+		}
+
+		fmt.Fprintf(bw, "%s\t%d\tIN\t%s\t%s\n", name, int(rr.TTL.Seconds()), rr.Type, data)
+	}
+
+	return bw.Flush()
+}
+
+// primaryNameServer returns the target of the first NS record at the apex of
+// the zone, or DigitalOcean's first name server if there is none.
+func primaryNameServer(zone string, records []libdns.Record) string {
+	for _, record := range records {
+		rr := record.RR()
+		if name := relativeName(rr.Name, zone); rr.Type == "NS" && (name == "@" || name == "") && rr.Data != "" {
+			return absoluteTarget(rr.Data, zone)
+		}
+	}
+	return soaNameServer
+}
+
+// zoneFileData returns the data of rr in master file presentation format.
+func zoneFileData(zone string, rr libdns.RR) (string, error) {
+	switch rr.Type {
+	case "TXT":
+		return quoteTXT(rr.Data), nil
+	case "CNAME", "NS":
+		return absoluteTarget(rr.Data, zone), nil
+	case "MX", "SRV", "CAA":
+		parsed, err := rr.Parse()
+		if err != nil {
+			return "", err
+		}
+
+		switch rec := parsed.(type) {
+		case libdns.MX:
+			return fmt.Sprintf("%d %s", rec.Preference, absoluteTarget(rec.Target, zone)), nil
+		case libdns.SRV:
+			return fmt.Sprintf("%d %d %d %s", rec.Priority, rec.Weight, rec.Port, absoluteTarget(rec.Target, zone)), nil
+		case libdns.CAA:
+			return fmt.Sprintf("%d %s %s", rec.Flags, rec.Tag, quoteString(rec.Value)), nil
+		}
+	}
+
+	return rr.Data, nil
+}
+
+// quoteTXT returns text as one or more quoted character-strings, split so that
+// no string exceeds 255 bytes.
+func quoteTXT(text string) string {
+	if len(text) <= maxTXTString {
+		return quoteString(text)
+	}
+
+	var parts []string
+	for len(text) > maxTXTString {
+		parts = append(parts, quoteString(text[:maxTXTString]))
+		text = text[maxTXTString:]
+	}
+	parts = append(parts, quoteString(text))
+
+	return strings.Join(parts, " ")
+}
+
+// quoteString returns s as a quoted master file character-string. Quotes and
+// backslashes are escaped with a backslash, other non-printable bytes as \DDD.
+func quoteString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			b.WriteString(fmt.Sprintf("\\%03d", c))
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// commonTTL returns the TTL most of the records share, for the $TTL directive,
+// or DigitalOcean's default TTL if there are no records.
+func commonTTL(records []libdns.Record) time.Duration {
+	counts := make(map[time.Duration]int)
+	best, bestCount := defaultTTL, 0
+	for _, record := range records {
+		rr := record.RR()
+		if rr.Type == "SOA" {
+			continue
+		}
+		ttl := rr.TTL
+		counts[ttl]++
+		if counts[ttl] > bestCount || (counts[ttl] == bestCount && ttl < best) {
+			best, bestCount = ttl, counts[ttl]
+		}
+	}
+	return best
+}
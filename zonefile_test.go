@@ -0,0 +1,89 @@
+package digitalocean
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+func TestZoneFile_ExportZone(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 1, Type: "SOA", Name: "@", Data: "1800", TTL: 1800},
+		{ID: 2, Type: "A", Name: "@", Data: "192.168.1.1", TTL: 3600},
+		{ID: 8, Type: "NS", Name: "@", Data: "ns1.example.net", TTL: 3600},
+		{ID: 3, Type: "CNAME", Name: "www", Data: "@", TTL: 3600},
+		{ID: 4, Type: "MX", Name: "@", Data: "mail", Priority: 10, TTL: 3600},
+		{ID: 5, Type: "SRV", Name: "_sip._tcp", Data: "sip.example.net", Priority: 10, Weight: 20, Port: 5060, TTL: 300},
+		{ID: 6, Type: "CAA", Name: "@", Data: "letsencrypt.org", Flags: 0, Tag: "issue", TTL: 3600},
+		{ID: 7, Type: "TXT", Name: "@", Data: `v=spf1 include:"x" -all`, TTL: 3600},
+	}
+
+	p := setupTest(mockRecords, nil)
+
+	var buf bytes.Buffer
+	if err := p.ExportZone(context.Background(), "example.com.", &buf); err != nil {
+		t.Fatalf("Provider.ExportZone() error = %v", err)
+	}
+
+	want := strings.Join([]string{
+		"$ORIGIN example.com.",
+		"$TTL 3600",
+		"@\t3600\tIN\tSOA\tns1.example.net. hostmaster.example.com. 1 10800 3600 604800 3600",
+		"@\t3600\tIN\tA\t192.168.1.1",
+		"@\t3600\tIN\tNS\tns1.example.net.",
+		"www\t3600\tIN\tCNAME\texample.com.",
+		"@\t3600\tIN\tMX\t10 mail.example.com.",
+		"_sip._tcp\t300\tIN\tSRV\t10 20 5060 sip.example.net.",
+		"@\t3600\tIN\tCAA\t0 issue \"letsencrypt.org\"",
+		"@\t3600\tIN\tTXT\t\"v=spf1 include:\\\"x\\\" -all\"",
+		"",
+	}, "\n")
+	if got := buf.String(); got != want {
+		t.Errorf("Provider.ExportZone() =\n%s\nwant\n%s", got, want)
+	}
+
+	// Without NS records, DigitalOcean's first name server is the primary
+	buf.Reset()
+	if err := WriteZoneFile(&buf, "example.com", nil); err != nil {
+		t.Fatalf("WriteZoneFile() error = %v", err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if want := "@\t1800\tIN\tSOA\tns1.digitalocean.com. hostmaster.example.com. 1 10800 3600 604800 1800"; lines[2] != want {
+		t.Errorf("WriteZoneFile() wrote %q after the directives, want %q", lines[2], want)
+	}
+
+	// Test error case
+	p = setupTest(nil, errors.New("API error"))
+	if err := p.ExportZone(context.Background(), "example.com.", &buf); err == nil {
+		t.Error("Provider.ExportZone() expected error, got nil")
+	}
+}
+
+func TestZoneFile_quoteTXT(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{name: "plain", text: "hello world", want: `"hello world"`},
+		{name: "escapes", text: "a\"b\\c\td", want: `"a\"b\\c\009d"`},
+		{name: "empty", text: "", want: `""`},
+		{
+			name: "long",
+			text: strings.Repeat("a", 300),
+			want: `"` + strings.Repeat("a", 255) + `" "` + strings.Repeat("a", 45) + `"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quoteTXT(tt.text); got != tt.want {
+				t.Errorf("quoteTXT() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
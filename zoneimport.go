@@ -0,0 +1,501 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/libdns/libdns"
+)
+
+// ImportMode selects how ImportZone merges the records of a zone file into a
+// zone.
+type ImportMode int
+
+const (
+	// ImportAppend only creates the records of the zone file that are not in
+	// the zone yet. Nothing is edited or deleted.
+	ImportAppend ImportMode = iota
+	// ImportReplace makes the zone hold exactly the records of the zone file,
+	// through Plan and Apply. It fails with ErrSkippedEntries if entries of the
+	// file other than the SOA record, the NS records at the apex and names
+	// outside the zone were skipped, as the records they were meant to keep
+	// would be deleted.
+	ImportReplace
+)
+
+// ErrSkippedEntries is returned by ImportZone in ImportReplace mode when the
+// zone file holds entries that could not be imported.
+var ErrSkippedEntries = errors.New("zone file has entries that cannot be imported")
+
+// ZoneFileEntry is an entry of a zone file that could not be imported as it
+// was written.
+type ZoneFileEntry struct {
+	// Line is the line the entry starts on
+	Line int
+	// Text is the entry, with comments and line breaks removed
+	Text   string
+	Reason string
+
+	// expected is set for entries a zone file exported from another DNS
+	// server holds anyway, which no record of the zone corresponds to
+	expected bool
+}
+
+func (e ZoneFileEntry) String() string {
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.Text, e.Reason)
+}
+
+// ImportReport describes the outcome of ImportZone and ParseZoneFile.
+type ImportReport struct {
+	// Records are the records that were created or edited by ImportZone, or
+	// the records read by ParseZoneFile
+	Records []libdns.Record
+	// Skipped are the entries that were not imported, such as the SOA record,
+	// the NS records at the apex and record types DigitalOcean does not support
+	Skipped []ZoneFileEntry
+	// Adjusted are the entries that were imported with changes, such as a TTL
+	// raised to DigitalOcean's minimum
+	Adjusted []ZoneFileEntry
+}
+
+// ImportZone reads an RFC 1035 master file from r and adds its records to the
+// zone, according to mode. See ParseZoneFile for how the file is read. The
+// returned report lists the entries that were skipped or adjusted; it is
+// returned along with a *BatchError if some of the records could not be
+// imported.
+func (p *Provider) ImportZone(ctx context.Context, zone string, r io.Reader, mode ImportMode) (*ImportReport, error) {
+	report, err := ParseZoneFile(r, zone)
+	if err != nil {
+		return nil, err
+	}
+	records := report.Records
+	report.Records = nil
+
+	switch mode {
+	case ImportReplace:
+		for _, entry := range report.Skipped {
+			if !entry.expected {
+				return report, fmt.Errorf("digitalocean: not replacing zone %s: %w: %s", p.unFQDN(zone), ErrSkippedEntries, entry)
+			}
+		}
+
+		plan, err := p.Plan(ctx, zone, records)
+		if err != nil {
+			return report, err
+		}
+		report.Records, err = p.Apply(ctx, plan)
+		return report, err
+
+	case ImportAppend:
+		existing, err := p.GetRecords(ctx, zone)
+		if err != nil {
+			return report, err
+		}
+
+		var missing []libdns.Record
+		for _, record := range records {
+			normalized, err := normalizeRecord(p.unFQDN(zone), record)
+			if err != nil {
+				return report, RecordError{Record: record, Err: err}
+			}
+			want := normalized.RR()
+			found := false
+			for _, have := range existing {
+				rr := have.RR()
+				if rr.Name == want.Name && rr.Type == want.Type && rr.Data == want.Data {
+					found = true
+					break
+				}
+			}
+			if !found {
+				missing = append(missing, record)
+			}
+		}
+		if len(missing) == 0 {
+			return report, nil
+		}
+
+		report.Records, err = p.AppendRecords(ctx, zone, missing)
+		return report, err
+
+	default:
+		return nil, fmt.Errorf("unknown import mode %d", mode)
+	}
+}
+
+// ParseZoneFile reads an RFC 1035 master file from r and returns its records
+// for zone, with names relative to the zone, in the Records of the report.
+// $ORIGIN and $TTL directives, relative names, parenthesized multi-line entries
+// and TXT records made of several strings are understood; $INCLUDE is not.
+//
+// Entries DigitalOcean cannot hold are listed in Skipped: the SOA record and
+// the NS records at the apex, which DigitalOcean manages itself, records of
+// other classes than IN, names outside the zone, unsupported record types and
+// malformed entries. TTLs below DigitalOcean's minimum are raised to it and
+// listed in Adjusted. An error is only returned for files that cannot be read
+// at all, such as ones with unbalanced quotes or parentheses.
+func ParseZoneFile(r io.Reader, zone string) (*ImportReport, error) {
+	input, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := zoneFileEntries(string(input))
+	if err != nil {
+		return nil, err
+	}
+
+	zone = strings.TrimSuffix(zone, ".")
+	origin := zone + "."
+	var zoneTTL, lastTTL time.Duration
+	var owner string
+
+	var records []libdns.Record
+	var skipped, adjusted []ZoneFileEntry
+	skip := func(e zoneFileEntry, format string, args ...any) {
+		skipped = append(skipped, ZoneFileEntry{Line: e.line, Text: e.String(), Reason: fmt.Sprintf(format, args...)})
+	}
+	ignore := func(e zoneFileEntry, format string, args ...any) {
+		skip(e, format, args...)
+		skipped[len(skipped)-1].expected = true
+	}
+
+	for _, e := range entries {
+		fields := e.fields
+
+		if !e.blankOwner && strings.HasPrefix(fields[0].text, "$") {
+			switch directive := strings.ToUpper(fields[0].text); directive {
+			case "$ORIGIN":
+				if len(fields) != 2 {
+					return nil, fmt.Errorf("line %d: $ORIGIN takes one name", e.line)
+				}
+				origin = absoluteName(fields[1].text, origin)
+			case "$TTL":
+				if len(fields) != 2 {
+					return nil, fmt.Errorf("line %d: $TTL takes one TTL", e.line)
+				}
+				ttl, ok := parseTTL(fields[1].text)
+				if !ok {
+					return nil, fmt.Errorf("line %d: invalid TTL %q", e.line, fields[1].text)
+				}
+				zoneTTL = ttl
+			default:
+				skip(e, "%s is not supported", directive)
+			}
+			continue
+		}
+
+		if !e.blankOwner {
+			owner = absoluteName(fields[0].text, origin)
+			fields = fields[1:]
+		} else if owner == "" {
+			skip(e, "no owner name")
+			continue
+		}
+
+		// the TTL and class may come in either order
+		ttl, class := time.Duration(-1), "IN"
+		for i := 0; i < 2 && len(fields) > 0; i++ {
+			if t, ok := parseTTL(fields[0].text); ok && ttl < 0 {
+				ttl = t
+				fields = fields[1:]
+			} else if isClass(fields[0].text) {
+				class = strings.ToUpper(fields[0].text)
+				fields = fields[1:]
+			}
+		}
+		switch {
+		case ttl >= 0:
+			lastTTL = ttl
+		case zoneTTL > 0:
+			ttl = zoneTTL
+		default:
+			ttl = lastTTL
+		}
+
+		if len(fields) == 0 {
+			skip(e, "no record type")
+			continue
+		}
+		recType := strings.ToUpper(fields[0].text)
+		rdata := fields[1:]
+
+		name := relativeName(owner, zone)
+		switch {
+		case class != "IN":
+			skip(e, "class %s is not supported", class)
+			continue
+		case name == owner:
+			ignore(e, "name is outside of zone %s", zone)
+			continue
+		case managedByDigitalOcean(libdns.RR{Name: name, Type: recType}):
+			ignore(e, "%s records at the apex are managed by DigitalOcean", recType)
+			continue
+		}
+
+		data, err := zoneFileRecordData(recType, rdata, origin)
+		if err != nil {
+			skip(e, "%v", err)
+			continue
+		}
+
+		rr := libdns.RR{Name: name, Type: recType, TTL: ttl, Data: data}
+		if clamped := clampTTL(ttl); clamped != ttl && ttl != 0 {
+			adjusted = append(adjusted, ZoneFileEntry{Line: e.line, Text: e.String(),
+				Reason: fmt.Sprintf("TTL raised to DigitalOcean's minimum of %s", minTTL)})
+			rr.TTL = clamped
+		}
+
+		record, err := rr.Parse()
+		if err != nil {
+			skip(e, "%v", err)
+			continue
+		}
+		records = append(records, record)
+	}
+
+	return &ImportReport{Records: records, Skipped: skipped, Adjusted: adjusted}, nil
+}
+
+// zoneFileRecordData converts the rdata fields of a master file entry to the
+// data of a libdns.RR, for the record types DigitalOcean supports. Target names
+// are made fully qualified relative to origin.
+func zoneFileRecordData(recType string, rdata []zoneFileField, origin string) (string, error) {
+	want := map[string]int{"A": 1, "AAAA": 1, "CNAME": 1, "NS": 1, "MX": 2, "SRV": 4, "CAA": 3}
+
+	switch recType {
+	case "TXT":
+		if len(rdata) == 0 {
+			return "", fmt.Errorf("malformed TXT record")
+		}
+		var text strings.Builder
+		for _, field := range rdata {
+			text.WriteString(field.text)
+		}
+		return text.String(), nil
+	case "A", "AAAA", "CNAME", "NS", "MX", "SRV", "CAA":
+		if len(rdata) != want[recType] {
+			return "", fmt.Errorf("malformed %s record", recType)
+		}
+	default:
+		return "", fmt.Errorf("record type %s is not supported by DigitalOcean", recType)
+	}
+
+	last := len(rdata) - 1
+	texts := make([]string, len(rdata))
+	for i, field := range rdata {
+		texts[i] = field.text
+	}
+
+	switch recType {
+	case "CNAME", "NS", "MX", "SRV":
+		texts[last] = absoluteName(texts[last], origin)
+	case "CAA":
+		texts[last] = strconv.Quote(texts[last])
+	}
+
+	return strings.Join(texts, " "), nil
+}
+
+// absoluteName returns name fully qualified, taking "@" and names without a
+// trailing dot to be relative to origin.
+func absoluteName(name, origin string) string {
+	switch {
+	case name == "@":
+		return origin
+	case strings.HasSuffix(name, "."):
+		return name
+	default:
+		return name + "." + origin
+	}
+}
+
+// parseTTL parses a TTL in seconds, or in the BIND notation with units such as
+// "1h30m".
+func parseTTL(s string) (time.Duration, bool) {
+	if s == "" || s[0] < '0' || s[0] > '9' {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseUint(s, 10, 31); err == nil {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	units := map[byte]time.Duration{'s': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour}
+	var ttl time.Duration
+	start := 0
+	for i := 0; i < len(s); i++ {
+		unit, ok := units[s[i]|0x20]
+		if !ok {
+			continue
+		}
+		n, err := strconv.ParseUint(s[start:i], 10, 31)
+		if err != nil {
+			return 0, false
+		}
+		ttl += time.Duration(n) * unit
+		start = i + 1
+	}
+	if start != len(s) {
+		return 0, false
+	}
+	return ttl, true
+}
+
+// isClass reports whether s is a DNS class.
+func isClass(s string) bool {
+	switch strings.ToUpper(s) {
+	case "IN", "CH", "HS", "CS":
+		return true
+	default:
+		return false
+	}
+}
+
+// zoneFileField is a field of a master file entry.
+type zoneFileField struct {
+	text   string
+	quoted bool
+}
+
+// zoneFileEntry is a logical line of a master file: a directive or a record.
+type zoneFileEntry struct {
+	line int
+	// blankOwner is set for entries starting with whitespace, which belong to
+	// the owner of the previous entry
+	blankOwner bool
+	fields     []zoneFileField
+}
+
+func (e zoneFileEntry) String() string {
+	texts := make([]string, len(e.fields))
+	for i, field := range e.fields {
+		if field.quoted {
+			texts[i] = quoteString(field.text)
+		} else {
+			texts[i] = field.text
+		}
+	}
+	return strings.Join(texts, " ")
+}
+
+// zoneFileEntries splits a master file into entries, removing comments and
+// joining the lines of parenthesized entries. Escapes in quoted strings are
+// resolved.
+func zoneFileEntries(input string) ([]zoneFileEntry, error) {
+	var entries []zoneFileEntry
+	var current zoneFileEntry
+	line, depth := 1, 0
+	startOfLine := true
+
+	flush := func() {
+		if len(current.fields) > 0 {
+			entries = append(entries, current)
+		}
+		current = zoneFileEntry{}
+	}
+
+	for i := 0; i < len(input); {
+		c := input[i]
+		switch {
+		case c == '\n':
+			line++
+			i++
+			if depth == 0 {
+				flush()
+				startOfLine = true
+			}
+			continue
+		case c == ' ' || c == '\t' || c == '\r':
+			if startOfLine && depth == 0 && len(current.fields) == 0 {
+				current.blankOwner = true
+			}
+			i++
+		case c == ';':
+			for i < len(input) && input[i] != '\n' {
+				i++
+			}
+		case c == '(':
+			depth++
+			i++
+		case c == ')':
+			if depth == 0 {
+				return nil, fmt.Errorf("line %d: unbalanced parenthesis", line)
+			}
+			depth--
+			i++
+		case c == '"':
+			text, n, err := unquoteField(input[i:])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			if len(current.fields) == 0 {
+				current.line = line
+			}
+			current.fields = append(current.fields, zoneFileField{text: text, quoted: true})
+			line += strings.Count(input[i:i+n], "\n")
+			i += n
+		default:
+			start := i
+			for i < len(input) && !strings.ContainsRune(" \t\r\n;()\"", rune(input[i])) {
+				if input[i] == '\\' && i+1 < len(input) {
+					i++
+				}
+				i++
+			}
+			if len(current.fields) == 0 {
+				current.line = line
+			}
+			current.fields = append(current.fields, zoneFileField{text: input[start:i]})
+		}
+		startOfLine = false
+	}
+
+	if depth != 0 {
+		return nil, fmt.Errorf("line %d: unbalanced parenthesis", line)
+	}
+	flush()
+
+	return entries, nil
+}
+
+// unquoteField reads the quoted string at the start of s and returns its
+// contents with escapes resolved, and the number of bytes read.
+func unquoteField(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '"':
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+3 < len(s) && isDigits(s[i+1:i+4]) {
+				n, _ := strconv.Atoi(s[i+1 : i+4])
+				if n > 255 {
+					return "", 0, fmt.Errorf("invalid escape \\%s", s[i+1:i+4])
+				}
+				b.WriteByte(byte(n))
+				i += 3
+			} else if i+1 < len(s) {
+				b.WriteByte(s[i+1])
+				i++
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted string")
+}
+
+// isDigits reports whether s consists of decimal digits only.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return s != ""
+}
@@ -0,0 +1,132 @@
+package digitalocean
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/digitalocean/godo"
+	"github.com/libdns/libdns"
+)
+
+const testZoneFile = `$ORIGIN example.com.
+$TTL 1h
+@	IN	SOA	ns1.example.com. hostmaster.example.com. (
+		2024010101 ; serial
+		7200       ; refresh
+		3600       ; retry
+		1209600    ; expire
+		300 )      ; minimum
+	IN	NS	ns1.digitalocean.com.
+	IN	A	192.168.1.1
+	300	IN	MX	10 mail
+www	CNAME	@
+mail	IN	10	A	192.168.1.2
+txt	TXT	( "v=spf1 "
+	  "include:_spf.example.net -all" )
+esc	TXT	"say \"hi\"\059"
+caa	CAA	0 issue "letsencrypt.org"
+short	5	A	192.168.1.3
+$ORIGIN sub.example.com.
+_sip._tcp	SRV	10 20 5060 sip
+other.org.	A	192.168.1.4
+old	CH	A	192.168.1.5
+spf	SPF	"v=spf1 -all"
+`
+
+func TestZoneImport_ParseZoneFile(t *testing.T) {
+	report, err := ParseZoneFile(strings.NewReader(testZoneFile), "example.com.")
+	if err != nil {
+		t.Fatalf("ParseZoneFile() error = %v", err)
+	}
+
+	want := []libdns.RR{
+		{Name: "@", Type: "A", TTL: time.Hour, Data: "192.168.1.1"},
+		{Name: "@", Type: "MX", TTL: 300 * time.Second, Data: "10 mail.example.com."},
+		{Name: "www", Type: "CNAME", TTL: time.Hour, Data: "example.com."},
+		{Name: "mail", Type: "A", TTL: 30 * time.Second, Data: "192.168.1.2"},
+		{Name: "txt", Type: "TXT", TTL: time.Hour, Data: "v=spf1 include:_spf.example.net -all"},
+		{Name: "esc", Type: "TXT", TTL: time.Hour, Data: `say "hi";`},
+		{Name: "caa", Type: "CAA", TTL: time.Hour, Data: `0 issue "letsencrypt.org"`},
+		{Name: "short", Type: "A", TTL: 30 * time.Second, Data: "192.168.1.3"},
+		{Name: "_sip._tcp.sub", Type: "SRV", TTL: time.Hour, Data: "10 20 5060 sip.sub.example.com."},
+	}
+	if len(report.Records) != len(want) {
+		t.Fatalf("ParseZoneFile() = %v, want %d records", report.Records, len(want))
+	}
+	for i, record := range report.Records {
+		if got := record.RR(); got != want[i] {
+			t.Errorf("ParseZoneFile()[%d] = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	// SOA, apex NS, out of zone name, other class and unsupported type
+	wantSkipped := []int{3, 9, 21, 22, 23}
+	if len(report.Skipped) != len(wantSkipped) {
+		t.Fatalf("ParseZoneFile() skipped = %v, want lines %v", report.Skipped, wantSkipped)
+	}
+	for i, entry := range report.Skipped {
+		if entry.Line != wantSkipped[i] {
+			t.Errorf("ParseZoneFile() skipped[%d] = %v, want line %d", i, entry, wantSkipped[i])
+		}
+	}
+
+	if len(report.Adjusted) != 2 || report.Adjusted[0].Line != 13 || report.Adjusted[1].Line != 18 {
+		t.Errorf("ParseZoneFile() adjusted = %v, want lines 13 and 18", report.Adjusted)
+	}
+
+	// Test error cases
+	for _, input := range []string{"@ TXT \"unterminated\n", "@ SOA ( ns1 hostmaster\n", "$TTL forever\n"} {
+		if _, err := ParseZoneFile(strings.NewReader(input), "example.com."); err == nil {
+			t.Errorf("ParseZoneFile(%q) expected error, got nil", input)
+		}
+	}
+}
+
+func TestZoneImport_ImportZone(t *testing.T) {
+	mockRecords := []godo.DomainRecord{
+		{ID: 1, Type: "A", Name: "@", Data: "192.168.1.1", TTL: 3600},
+		{ID: 2, Type: "A", Name: "stale", Data: "192.168.1.9", TTL: 3600},
+	}
+	zoneFile := "@ 3600 IN A 192.168.1.1\nwww 3600 IN A 192.168.1.2\n"
+	ctx := context.Background()
+
+	// Appending only creates the missing record
+	p := setupTest(mockRecords, nil)
+	mock := p.client.Domains.(*mockDomainsService)
+
+	report, err := p.ImportZone(ctx, "example.com.", strings.NewReader(zoneFile), ImportAppend)
+	if err != nil {
+		t.Fatalf("Provider.ImportZone() error = %v", err)
+	}
+	if len(report.Records) != 1 || report.Records[0].RR().Name != "www" || len(mock.deleted) != 0 {
+		t.Errorf("Provider.ImportZone() = %v, deleted = %v, want www created only", report.Records, mock.deleted)
+	}
+
+	// Replacing also deletes the records missing from the file
+	p = setupTest(mockRecords, nil)
+	mock = p.client.Domains.(*mockDomainsService)
+
+	report, err = p.ImportZone(ctx, "example.com.", strings.NewReader(zoneFile), ImportReplace)
+	if err != nil {
+		t.Fatalf("Provider.ImportZone() error = %v", err)
+	}
+	if len(report.Records) != 1 || len(mock.deleted) != 1 || mock.deleted[0] != 2 {
+		t.Errorf("Provider.ImportZone() = %v, deleted = %v, want www created and stale deleted", report.Records, mock.deleted)
+	}
+
+	// Replacing is refused when entries were skipped, as their records would be deleted
+	p = setupTest(mockRecords, nil)
+	mock = p.client.Domains.(*mockDomainsService)
+
+	zoneFile = "@ 3600 IN SOA ns1 hostmaster 1 2 3 4 5\n@ 3600 IN A 192.168.1.1\nstale 3600 IN A 192.168.1.9 extra\n"
+	report, err = p.ImportZone(ctx, "example.com.", strings.NewReader(zoneFile), ImportReplace)
+	if !errors.Is(err, ErrSkippedEntries) {
+		t.Errorf("Provider.ImportZone() error = %v, want ErrSkippedEntries", err)
+	}
+	if report == nil || len(report.Skipped) != 2 || len(mock.deleted) != 0 || len(mock.edited) != 0 {
+		t.Errorf("Provider.ImportZone() = %v, deleted = %v, want nothing changed", report, mock.deleted)
+	}
+}